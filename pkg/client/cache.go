@@ -0,0 +1,109 @@
+package client
+
+import (
+	"sync"
+
+	"github.com/valkey-io/valkey-go"
+)
+
+// cachedClient is a Valkey client shared by the ClientArgs with the same settings.
+// It is closed once the last ClientArgs using it is closed.
+type cachedClient struct {
+	key      string
+	instance valkey.Client
+	refs     int
+	closed   bool
+}
+
+var clientCache = make(map[string]*cachedClient)
+var cacheMutex = sync.Mutex{}
+
+// acquire returns the client cached for a key with one more reference, or nil if there is none.
+func acquire(key string) *cachedClient {
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+
+	entry, exists := clientCache[key]
+	if !exists {
+		return nil
+	}
+	entry.refs++
+	return entry
+}
+
+// store caches a new client with one reference. If another client was cached for the same key
+// in the meantime, the new client is closed and the cached one is returned instead.
+func store(key string, instance valkey.Client) *cachedClient {
+	cacheMutex.Lock()
+	entry, exists := clientCache[key]
+	if exists {
+		entry.refs++
+		cacheMutex.Unlock()
+		instance.Close()
+		return entry
+	}
+
+	entry = &cachedClient{key: key, instance: instance, refs: 1}
+	clientCache[key] = entry
+	cacheMutex.Unlock()
+	return entry
+}
+
+// evict removes a client from the cache, so the next InitClient creates a new one.
+// The client stays open until its last reference is released.
+func evict(entry *cachedClient) {
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+
+	if clientCache[entry.key] == entry {
+		delete(clientCache, entry.key)
+	}
+}
+
+// release drops a reference to a client, and removes it from the cache and closes it
+// when no reference is left.
+func release(entry *cachedClient) {
+	cacheMutex.Lock()
+	entry.refs--
+	if entry.refs > 0 || entry.closed {
+		cacheMutex.Unlock()
+		return
+	}
+	if clientCache[entry.key] == entry {
+		delete(clientCache, entry.key)
+	}
+	entry.closed = true
+	cacheMutex.Unlock()
+
+	entry.instance.Close()
+}
+
+// Close releases the Valkey client obtained by InitClient. The client is closed once every
+// ClientArgs sharing it is closed. Close does nothing if InitClient was not called, or if the
+// client was set in Instance directly.
+func (r *ClientArgs) Close() {
+	if r.cached == nil {
+		return
+	}
+	release(r.cached)
+	r.cached = nil
+}
+
+// CloseAll closes every cached Valkey client and empties the cache, for process shutdown.
+// ClientArgs still using a closed client get valkey.ErrClosing from its commands.
+func CloseAll() {
+	cacheMutex.Lock()
+	entries := make([]*cachedClient, 0, len(clientCache))
+	for key, entry := range clientCache {
+		delete(clientCache, key)
+		if !entry.closed {
+			entry.closed = true
+			entries = append(entries, entry)
+		}
+	}
+	cacheMutex.Unlock()
+
+	for _, entry := range entries {
+		entry.instance.Close()
+	}
+}
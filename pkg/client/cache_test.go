@@ -0,0 +1,98 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/valkey-io/valkey-go"
+)
+
+// ping sends a PING command with a client.
+func ping(client valkey.Client) error {
+	return client.Do(context.Background(), client.B().Ping().Build()).Error()
+}
+
+func TestClientCacheReferences(t *testing.T) {
+	port := startStandIn(t, nil)
+	ctx := context.Background()
+
+	first := ClientArgs{Host: "127.0.0.1", Port: port, DialTimeout: time.Second}
+	second := ClientArgs{Host: "127.0.0.1", Port: port, DialTimeout: time.Second}
+	err := first.InitClient(ctx)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	err = second.InitClient(ctx)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if first.Instance != second.Instance {
+		t.Fatalf("Error: client not shared")
+	}
+
+	first.Close()
+	err = ping(second.Instance)
+	if err != nil {
+		t.Fatalf("Error: client closed while still used: %v", err)
+	}
+
+	second.Close()
+	err = ping(second.Instance)
+	if !errors.Is(err, valkey.ErrClosing) {
+		t.Fatalf("Error: expected %v, got %v", valkey.ErrClosing, err)
+	}
+	if acquire(second.cacheKey()) != nil {
+		t.Fatalf("Error: closed client still cached")
+	}
+}
+
+func TestClientCacheEvictsBrokenClient(t *testing.T) {
+	port := startStandIn(t, nil)
+	ctx := context.Background()
+
+	first := ClientArgs{Host: "127.0.0.1", Port: port, DialTimeout: time.Second}
+	err := first.InitClient(ctx)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	defer first.Close()
+	broken := first.Instance
+	broken.Close()
+
+	second := ClientArgs{Host: "127.0.0.1", Port: port, DialTimeout: time.Second}
+	err = second.InitClient(ctx)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	defer second.Close()
+	if second.Instance == broken {
+		t.Fatalf("Error: broken client reused")
+	}
+	err = ping(second.Instance)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+}
+
+func TestCloseAll(t *testing.T) {
+	port := startStandIn(t, nil)
+
+	c := ClientArgs{Host: "127.0.0.1", Port: port, DialTimeout: time.Second}
+	err := c.InitClient(context.Background())
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	CloseAll()
+	err = ping(c.Instance)
+	if !errors.Is(err, valkey.ErrClosing) {
+		t.Fatalf("Error: expected %v, got %v", valkey.ErrClosing, err)
+	}
+	if acquire(c.cacheKey()) != nil {
+		t.Fatalf("Error: client still cached")
+	}
+
+	c.Close()
+}
@@ -2,41 +2,159 @@ package client
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
-	"sync"
+	"net"
+	"strings"
+	"time"
 
 	"github.com/valkey-io/valkey-go"
 )
 
 type ClientArgs struct {
-	Host     string
-	Port     string
+	Host string
+	Port string
+	// Addresses are more addresses to connect to, along with Host and Port when Host is set:
+	// the seed nodes of a Valkey Cluster, or the sentinels when Sentinel is set.
+	Addresses []string
+	// Cluster marks a Valkey Cluster deployment. valkey-go discovers the topology from any seed node;
+	// the flag makes consumers check that the keys they use together map to the same hash slot.
+	Cluster bool
+	// Sentinel connects to the master of a Valkey Sentinel master set, discovered through the sentinels
+	// at Host and Port and Addresses.
+	Sentinel *SentinelArgs
+
+	// Username and Password authenticate the connection, with AUTH or an ACL user.
+	Username string
+	Password string
+	// DB is the database index selected on the connection.
+	DB int
+	// ClientName is the name set on the connection with CLIENT SETNAME.
+	ClientName string
+	// DialTimeout bounds the time to establish a connection. Zero uses the valkey-go default.
+	DialTimeout time.Duration
+	// TLS enables TLS on the connection when set.
+	TLS *TLSArgs
+
+	// Option is the base valkey.ClientOption used to create the client, for settings not exposed above.
+	// Its InitAddress is replaced by Host, Port and Addresses, and the fields above override it when set.
+	// Clients built from different Option pointers are never shared through the cache.
+	Option *valkey.ClientOption
+
 	Instance valkey.Client
+
+	// cached is the shared client held by this ClientArgs, released by Close.
+	cached *cachedClient
+}
+
+// SentinelArgs configures the connection through Valkey Sentinel.
+type SentinelArgs struct {
+	// MasterSet is the name of the master set monitored by the sentinels.
+	MasterSet string
+	// Username and Password authenticate the connections to the sentinels.
+	// The master uses the ClientArgs credentials.
+	Username string
+	Password string
+}
+
+// addresses returns the Valkey addresses: Host and Port when Host is set, followed by Addresses.
+func (r *ClientArgs) addresses() []string {
+	addresses := make([]string, 0, len(r.Addresses)+1)
+	if r.Host != "" {
+		addresses = append(addresses, net.JoinHostPort(r.Host, r.Port))
+	}
+	return append(addresses, r.Addresses...)
+}
+
+// options builds the valkey.ClientOption used to create the client.
+func (r *ClientArgs) options() (valkey.ClientOption, error) {
+	var option valkey.ClientOption
+	if r.Option != nil {
+		option = *r.Option
+	}
+
+	option.InitAddress = r.addresses()
+	if r.Username != "" {
+		option.Username = r.Username
+	}
+	if r.Password != "" {
+		option.Password = r.Password
+	}
+	if r.DB != 0 {
+		option.SelectDB = r.DB
+	}
+	if r.ClientName != "" {
+		option.ClientName = r.ClientName
+	}
+	if r.DialTimeout != 0 {
+		option.Dialer.Timeout = r.DialTimeout
+	}
+	if r.TLS != nil {
+		config, err := r.TLS.config()
+		if err != nil {
+			return option, err
+		}
+		option.TLSConfig = config
+	}
+	if r.Sentinel != nil {
+		option.Sentinel.MasterSet = r.Sentinel.MasterSet
+		option.Sentinel.Username = r.Sentinel.Username
+		option.Sentinel.Password = r.Sentinel.Password
+		option.Sentinel.ClientName = option.ClientName
+		option.Sentinel.Dialer = option.Dialer
+		option.Sentinel.TLSConfig = option.TLSConfig
+	}
+
+	return option, nil
 }
 
-var clientCache = make(map[string]valkey.Client)
-var cacheMutex = sync.RWMutex{}
+// cacheKey identifies the clients that can be shared through the cache.
+// It covers every connection setting, so clients with different credentials are never shared.
+// The password is hashed so it is not kept in clear in the key.
+func (r *ClientArgs) cacheKey() string {
+	password := sha256.Sum256([]byte(r.Password))
+	key := fmt.Sprintf("%s|%s|%s|%d|%s|%s|%p", strings.Join(r.addresses(), ","), r.Username, hex.EncodeToString(password[:]), r.DB, r.ClientName, r.DialTimeout, r.Option)
+	if r.TLS != nil {
+		key += fmt.Sprintf("|tls|%p|%s|%s|%s|%s|%t", r.TLS.Config, r.TLS.CAFile, r.TLS.CertFile, r.TLS.KeyFile, r.TLS.ServerName, r.TLS.InsecureSkipVerify)
+	}
+	if r.Sentinel != nil {
+		password := sha256.Sum256([]byte(r.Sentinel.Password))
+		key += fmt.Sprintf("|sentinel|%s|%s|%s", r.Sentinel.MasterSet, r.Sentinel.Username, hex.EncodeToString(password[:]))
+	}
+	return key
+}
 
 // InitClient creates a new Valkey client or reuses an existing one from the cache.
-// It takes a context.Context as input and uses the ClientArgs receiver to access the Host and Port fields.
-// The Valkey client is created with the specified Valkey address if not already in the cache.
-// It then sends a PING command to the Valkey server to check the connection.
+// It takes a context.Context as input and uses the ClientArgs receiver to access the connection settings.
+// A cached client with the same settings is reused if it still answers a PING command; otherwise it is
+// evicted from the cache and a new client is created, then checked with a PING command.
+// Every successful call must be matched by a call to Close once the client is no longer needed.
 // The function returns any error encountered during client creation or the PING command.
 func (r *ClientArgs) InitClient(ctx context.Context) error {
-	redisAddress := fmt.Sprintf("%s:%s", r.Host, r.Port)
+	key := r.cacheKey()
 
-	// Check if we already have a client for this address
-	cacheMutex.RLock()
-	cachedClient, exists := clientCache[redisAddress]
-	cacheMutex.RUnlock()
-
-	if exists {
-		r.Instance = cachedClient
-		return nil
+	// Reuse the cached client for these settings if it is still healthy
+	if entry := acquire(key); entry != nil {
+		err := entry.instance.Do(ctx, entry.instance.B().Ping().Build()).Error()
+		if err == nil {
+			r.use(entry)
+			return nil
+		}
+		if ctx.Err() != nil {
+			release(entry)
+			return err
+		}
+		evict(entry)
+		release(entry)
 	}
 
 	// Create a new client if none exists
-	client, err := valkey.NewClient(valkey.ClientOption{InitAddress: []string{redisAddress}})
+	option, err := r.options()
+	if err != nil {
+		return err
+	}
+	client, err := valkey.NewClient(option)
 	if err != nil {
 		return err
 	}
@@ -44,14 +162,21 @@ func (r *ClientArgs) InitClient(ctx context.Context) error {
 	// Test the connection
 	err = client.Do(ctx, client.B().Ping().Build()).Error()
 	if err != nil {
+		client.Close()
 		return err
 	}
 
 	// Store the client in the cache
-	cacheMutex.Lock()
-	clientCache[redisAddress] = client
-	cacheMutex.Unlock()
-
-	r.Instance = client
+	r.use(store(key, client))
 	return nil
 }
+
+// use makes the ClientArgs use a cached client, releasing the one it held before.
+func (r *ClientArgs) use(entry *cachedClient) {
+	previous := r.cached
+	r.cached = entry
+	r.Instance = entry.instance
+	if previous != nil {
+		release(previous)
+	}
+}
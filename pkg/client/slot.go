@@ -0,0 +1,33 @@
+package client
+
+import "strings"
+
+// client_CLUSTER_SLOTS is the number of hash slots of a Valkey Cluster.
+const client_CLUSTER_SLOTS = 16384
+
+// HashSlot returns the Valkey Cluster hash slot of a key.
+// Only the hash tag is hashed when the key has one, so keys sharing a tag such as {orders} map to the same slot.
+func HashSlot(key string) uint16 {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+	return crc16(key) % client_CLUSTER_SLOTS
+}
+
+// crc16 computes the CRC16-CCITT (XMODEM) checksum used by Valkey Cluster.
+func crc16(key string) uint16 {
+	var crc uint16
+	for i := 0; i < len(key); i++ {
+		crc ^= uint16(key[i]) << 8
+		for range 8 {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
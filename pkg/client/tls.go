@@ -0,0 +1,65 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// TLSArgs configures TLS, and optionally mutual TLS, on the connection to Valkey.
+type TLSArgs struct {
+	// Config is the base tls.Config. It is cloned before the fields below are applied.
+	Config *tls.Config
+	// CAFile is a PEM bundle of the certificate authorities trusted to sign the server certificate.
+	// The system pool is used when empty.
+	CAFile string
+	// CertFile and KeyFile are the PEM client certificate and key presented for mutual TLS.
+	CertFile string
+	KeyFile  string
+	// ServerName overrides the name checked against the server certificate, which defaults to Host.
+	ServerName string
+	// InsecureSkipVerify disables the verification of the server certificate. Only use it in development.
+	InsecureSkipVerify bool
+}
+
+// config builds the tls.Config of the connection, loading the certificate files.
+func (t *TLSArgs) config() (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if t.Config != nil {
+		config = t.Config.Clone()
+	}
+
+	if t.CAFile != "" {
+		pem, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in CA file %s", t.CAFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if t.CertFile != "" || t.KeyFile != "" {
+		if t.CertFile == "" || t.KeyFile == "" {
+			return nil, errors.New("both CertFile and KeyFile must be set for mutual TLS")
+		}
+		certificate, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		config.Certificates = append(config.Certificates, certificate)
+	}
+
+	if t.ServerName != "" {
+		config.ServerName = t.ServerName
+	}
+	if t.InsecureSkipVerify {
+		config.InsecureSkipVerify = true
+	}
+
+	return config, nil
+}
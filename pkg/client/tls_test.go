@@ -0,0 +1,303 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCertificate is a certificate and its key, in PEM and parsed forms.
+type testCertificate struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// newTestCertificate creates a certificate signed by parent, or self-signed when parent is nil.
+func newTestCertificate(t *testing.T, template *x509.Certificate, parent *testCertificate) testCertificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	return testCertificate{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+// testPKI creates a CA with a server certificate for localhost and a client certificate.
+func testPKI(t *testing.T) (ca, server, client testCertificate) {
+	t.Helper()
+
+	now := time.Now()
+	ca = newTestCertificate(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "redsumer test CA"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}, nil)
+	server = newTestCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, &ca)
+	client = newTestCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "redsumer"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, &ca)
+	return ca, server, client
+}
+
+// writeFile writes content to a file in a temporary directory and returns its path.
+func writeFile(t *testing.T, name string, content []byte) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	err := os.WriteFile(path, content, 0o600)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	return path
+}
+
+// readCommand reads a RESP array of bulk strings.
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, n)
+	for i := range args {
+		line, err = r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+		if err != nil {
+			return nil, err
+		}
+		arg := make([]byte, size+2)
+		_, err = io.ReadFull(r, arg)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = string(arg[:size])
+	}
+	return args, nil
+}
+
+// serveStandIn answers just enough commands on a connection for a standalone valkey-go client to start.
+func serveStandIn(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+
+		var reply string
+		switch strings.ToUpper(args[0]) {
+		case "HELLO":
+			reply = "%2\r\n+proto\r\n:3\r\n+version\r\n+7.2.0\r\n"
+		case "CLUSTER":
+			reply = "-ERR This instance has cluster support disabled\r\n"
+		case "PING":
+			reply = "+PONG\r\n"
+		default:
+			reply = "+OK\r\n"
+		}
+		_, err = conn.Write([]byte(reply))
+		if err != nil {
+			return
+		}
+	}
+}
+
+// startStandIn starts a server standing in for Valkey and returns its port.
+// It serves TLS when config is not nil.
+func startStandIn(t *testing.T, config *tls.Config) string {
+	t.Helper()
+
+	var listener net.Listener
+	var err error
+	if config != nil {
+		listener, err = tls.Listen("tcp", "127.0.0.1:0", config)
+	} else {
+		listener, err = net.Listen("tcp", "127.0.0.1:0")
+	}
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveStandIn(conn)
+		}
+	}()
+
+	_, port, err := net.SplitHostPort(listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	return port
+}
+
+func TestInitClientMutualTLS(t *testing.T) {
+	ca, server, client := testPKI(t)
+
+	serverCert, err := tls.X509KeyPair(server.certPEM, server.keyPEM)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+	port := startStandIn(t, &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientCAs:    clientCAs,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	})
+
+	c := ClientArgs{
+		Host: "127.0.0.1",
+		Port: port,
+		TLS: &TLSArgs{
+			CAFile:     writeFile(t, "ca.pem", ca.certPEM),
+			CertFile:   writeFile(t, "client.pem", client.certPEM),
+			KeyFile:    writeFile(t, "client-key.pem", client.keyPEM),
+			ServerName: "localhost",
+		},
+		DialTimeout: time.Second,
+	}
+
+	err = c.InitClient(context.Background())
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	defer c.Close()
+
+	err = c.Instance.Do(context.Background(), c.Instance.B().Ping().Build()).Error()
+	if err != nil {
+		t.Errorf("Error: %v", err)
+	}
+}
+
+func TestInitClientInsecureSkipVerify(t *testing.T) {
+	_, server, _ := testPKI(t)
+
+	serverCert, err := tls.X509KeyPair(server.certPEM, server.keyPEM)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	port := startStandIn(t, &tls.Config{Certificates: []tls.Certificate{serverCert}})
+
+	c := ClientArgs{
+		Host:        "127.0.0.1",
+		Port:        port,
+		TLS:         &TLSArgs{InsecureSkipVerify: true},
+		DialTimeout: time.Second,
+	}
+
+	err = c.InitClient(context.Background())
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	c.Close()
+}
+
+func TestTLSArgsConfig(t *testing.T) {
+	ca, _, client := testPKI(t)
+
+	base := &tls.Config{MinVersion: tls.VersionTLS13}
+	args := TLSArgs{
+		Config:     base,
+		CAFile:     writeFile(t, "ca.pem", ca.certPEM),
+		CertFile:   writeFile(t, "client.pem", client.certPEM),
+		KeyFile:    writeFile(t, "client-key.pem", client.keyPEM),
+		ServerName: "valkey.internal",
+	}
+
+	config, err := args.config()
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if config.MinVersion != tls.VersionTLS13 || config.ServerName != "valkey.internal" {
+		t.Errorf("Error: unexpected config %+v", config)
+	}
+	if config.RootCAs == nil || len(config.Certificates) != 1 {
+		t.Errorf("Error: certificates not loaded")
+	}
+	if base.ServerName != "" || len(base.Certificates) != 0 {
+		t.Errorf("Error: base config modified")
+	}
+
+	_, err = (&TLSArgs{CertFile: args.CertFile}).config()
+	if err == nil {
+		t.Errorf("Error: expected error for missing key file")
+	}
+	_, err = (&TLSArgs{CAFile: args.CertFile + ".missing"}).config()
+	if err == nil {
+		t.Errorf("Error: expected error for missing CA file")
+	}
+}
@@ -0,0 +1,45 @@
+package codec
+
+import (
+	"fmt"
+
+	errors_custom "github.com/enerBit/redsumer/v3/pkg/errors"
+)
+
+const (
+	// FieldPayload is the entry field holding the encoded value, for codecs that encode it as a whole.
+	FieldPayload = "payload"
+	// FieldContentType is the entry field holding the media type of FieldPayload.
+	FieldContentType = "content-type"
+)
+
+// Codec converts values to and from the fields of a stream entry.
+type Codec interface {
+	// Encode returns the fields of the entry representing v.
+	Encode(v any) (map[string]string, error)
+	// Decode stores in the value pointed to by v the value represented by the fields of an entry.
+	Decode(fields map[string]string, v any) error
+}
+
+// EncodePayload returns the fields of an entry holding an encoded value and its content type.
+func EncodePayload(contentType string, data []byte) map[string]string {
+	return map[string]string{
+		FieldPayload:     string(data),
+		FieldContentType: contentType,
+	}
+}
+
+// DecodePayload returns the encoded value held by the fields of an entry.
+// It fails with ErrContentType when the entry declares another content type,
+// and with ErrDecode when it has no payload.
+func DecodePayload(fields map[string]string, contentType string) ([]byte, error) {
+	if declared, ok := fields[FieldContentType]; ok && declared != contentType {
+		return nil, fmt.Errorf("%w: %s, expected %s", errors_custom.ErrContentType, declared, contentType)
+	}
+
+	payload, ok := fields[FieldPayload]
+	if !ok {
+		return nil, fmt.Errorf("%w: no %s field", errors_custom.ErrDecode, FieldPayload)
+	}
+	return []byte(payload), nil
+}
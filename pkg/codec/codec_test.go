@@ -0,0 +1,50 @@
+package codec
+
+import (
+	"errors"
+	"testing"
+
+	errors_custom "github.com/enerBit/redsumer/v3/pkg/errors"
+)
+
+type order struct {
+	ID     string  `json:"id"`
+	Amount float64 `json:"amount"`
+}
+
+func TestJSON(t *testing.T) {
+	fields, err := JSON{}.Encode(order{ID: "1", Amount: 9.5})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if fields[FieldPayload] != `{"id":"1","amount":9.5}` || fields[FieldContentType] != ContentTypeJSON {
+		t.Fatalf("unexpected fields %v", fields)
+	}
+
+	var decoded order
+	err = JSON{}.Decode(fields, &decoded)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if decoded != (order{ID: "1", Amount: 9.5}) {
+		t.Fatalf("unexpected value %+v", decoded)
+	}
+}
+
+func TestJSONDecodeErrors(t *testing.T) {
+	tests := []struct {
+		fields map[string]string
+		err    error
+	}{
+		{map[string]string{FieldPayload: "{", FieldContentType: ContentTypeJSON}, errors_custom.ErrDecode},
+		{map[string]string{"id": "1"}, errors_custom.ErrDecode},
+		{map[string]string{FieldPayload: "{}", FieldContentType: "application/x-protobuf"}, errors_custom.ErrContentType},
+	}
+	for _, test := range tests {
+		var decoded order
+		err := JSON{}.Decode(test.fields, &decoded)
+		if !errors.Is(err, test.err) {
+			t.Fatalf("expected %v for %v, got %v", test.err, test.fields, err)
+		}
+	}
+}
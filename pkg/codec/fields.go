@@ -0,0 +1,219 @@
+package codec
+
+import (
+	"encoding"
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	errors_custom "github.com/enerBit/redsumer/v3/pkg/errors"
+)
+
+// codec_TAG is the struct tag naming the entry field of a struct field.
+const codec_TAG = "redsumer"
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	bytesType           = reflect.TypeOf([]byte(nil))
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// Fields maps every exported field of a struct to an entry field, so entries stay readable
+// and usable by consumers that do not share the Go type.
+//
+// The entry field is named by the redsumer tag, or after the struct field when it has none.
+// The tag "-" skips the field, and the option ",omitempty" leaves out zero values:
+//
+//	type Order struct {
+//		ID       string        `redsumer:"id"`
+//		Amount   float64       `redsumer:"amount"`
+//		Created  time.Time     `redsumer:"created_at"`
+//		Timeout  time.Duration `redsumer:"timeout,omitempty"`
+//		Internal string        `redsumer:"-"`
+//	}
+//
+// Supported field types are strings, booleans, integers, floats, time.Duration, []byte (as base64),
+// and the types implementing encoding.TextMarshaler and encoding.TextUnmarshaler, such as time.Time,
+// as well as pointers to them. A nil pointer is written as an empty field, and an empty field
+// decodes into a nil pointer. Entry fields missing when decoding leave the struct field unchanged.
+type Fields struct{}
+
+// field is a struct field mapped to an entry field.
+type field struct {
+	index     int
+	name      string
+	omitEmpty bool
+}
+
+// structFields returns the mapped fields of a struct type.
+func structFields(t reflect.Type) []field {
+	fields := make([]field, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		name, options, _ := strings.Cut(sf.Tag.Get(codec_TAG), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		fields = append(fields, field{index: i, name: name, omitEmpty: options == "omitempty"})
+	}
+	return fields
+}
+
+// Encode returns one entry field per field of the struct, or pointer to struct, v.
+func (Fields) Encode(v any) (map[string]string, error) {
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Pointer && !value.IsNil() {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("fields codec: cannot encode %T, expected a struct", v)
+	}
+
+	entry := make(map[string]string)
+	for _, f := range structFields(value.Type()) {
+		fv := value.Field(f.index)
+		if f.omitEmpty && fv.IsZero() {
+			continue
+		}
+
+		s, err := formatValue(fv)
+		if err != nil {
+			return nil, fmt.Errorf("fields codec: field %s: %w", f.name, err)
+		}
+		entry[f.name] = s
+	}
+	return entry, nil
+}
+
+// Decode sets the fields of the struct pointed to by v from the fields of an entry.
+func (Fields) Decode(fields map[string]string, v any) error {
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Pointer || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("fields codec: cannot decode into %T, expected a pointer to a struct", v)
+	}
+	value = value.Elem()
+
+	for _, f := range structFields(value.Type()) {
+		s, ok := fields[f.name]
+		if !ok {
+			continue
+		}
+
+		err := parseValue(value.Field(f.index), s)
+		if err != nil {
+			return fmt.Errorf("%w: field %s: %w", errors_custom.ErrDecode, f.name, err)
+		}
+	}
+	return nil
+}
+
+// formatValue returns the string representation of a field value.
+func formatValue(v reflect.Value) (string, error) {
+	if v.Kind() == reflect.Pointer && v.IsNil() {
+		return "", nil
+	}
+	if v.Type().Implements(textMarshalerType) {
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(text), err
+	}
+	if v.Kind() == reflect.Pointer {
+		return formatValue(v.Elem())
+	}
+
+	switch {
+	case v.Type() == durationType:
+		return time.Duration(v.Int()).String(), nil
+	case v.Type() == bytesType:
+		return base64.StdEncoding.EncodeToString(v.Bytes()), nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()), nil
+	}
+	return "", fmt.Errorf("unsupported type %s", v.Type())
+}
+
+// parseValue sets a field value from its string representation.
+func parseValue(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Pointer {
+		if s == "" {
+			v.SetZero()
+			return nil
+		}
+		target := reflect.New(v.Type().Elem())
+		err := parseValue(target.Elem(), s)
+		if err == nil {
+			v.Set(target)
+		}
+		return err
+	}
+	if reflect.PointerTo(v.Type()).Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(s)
+		if err == nil {
+			v.SetInt(int64(d))
+		}
+		return err
+	case v.Type() == bytesType:
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err == nil {
+			v.SetBytes(b)
+		}
+		return err
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+		return nil
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err == nil {
+			v.SetBool(b)
+		}
+		return err
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err == nil {
+			v.SetInt(n)
+		}
+		return err
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err == nil {
+			v.SetUint(n)
+		}
+		return err
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err == nil {
+			v.SetFloat(f)
+		}
+		return err
+	}
+	return fmt.Errorf("unsupported type %s", v.Type())
+}
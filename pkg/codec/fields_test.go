@@ -0,0 +1,114 @@
+package codec
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	errors_custom "github.com/enerBit/redsumer/v3/pkg/errors"
+)
+
+type event struct {
+	ID       string        `redsumer:"id"`
+	Count    int           `redsumer:"count"`
+	Ratio    float64       `redsumer:"ratio"`
+	Active   bool          `redsumer:"active"`
+	Created  time.Time     `redsumer:"created_at"`
+	Timeout  time.Duration `redsumer:"timeout,omitempty"`
+	Data     []byte        `redsumer:"data"`
+	Untagged uint8
+	Internal string `redsumer:"-"`
+	private  string
+}
+
+func TestFields(t *testing.T) {
+	value := event{
+		ID:       "1",
+		Count:    -3,
+		Ratio:    0.25,
+		Active:   true,
+		Created:  time.Date(2023, 2, 14, 15, 4, 37, 0, time.UTC),
+		Data:     []byte{0, 1, 2},
+		Untagged: 7,
+		Internal: "internal",
+		private:  "private",
+	}
+
+	fields, err := Fields{}.Encode(&value)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	expected := map[string]string{
+		"id":         "1",
+		"count":      "-3",
+		"ratio":      "0.25",
+		"active":     "true",
+		"created_at": "2023-02-14T15:04:37Z",
+		"data":       "AAEC",
+		"Untagged":   "7",
+	}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Fatalf("expected %v, got %v", expected, fields)
+	}
+
+	fields["timeout"] = "1m30s"
+	var decoded event
+	err = Fields{}.Decode(fields, &decoded)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	value.Timeout = 90 * time.Second
+	value.Internal, value.private = "", ""
+	if !reflect.DeepEqual(decoded, value) {
+		t.Fatalf("expected %+v, got %+v", value, decoded)
+	}
+}
+
+func TestFieldsErrors(t *testing.T) {
+	var decoded event
+	err := Fields{}.Decode(map[string]string{"count": "many"}, &decoded)
+	if !errors.Is(err, errors_custom.ErrDecode) {
+		t.Fatalf("expected %v, got %v", errors_custom.ErrDecode, err)
+	}
+
+	err = Fields{}.Decode(map[string]string{}, decoded)
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+
+	_, err = Fields{}.Encode("value")
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+
+	_, err = Fields{}.Encode(struct{ Tags []string }{})
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+}
+
+type optional struct {
+	Count   *int       `redsumer:"count"`
+	Name    *string    `redsumer:"name"`
+	Created *time.Time `redsumer:"created_at"`
+}
+
+func TestFieldsPointers(t *testing.T) {
+	count, name, created := 3, "order", time.Date(2023, 2, 14, 15, 4, 37, 0, time.UTC)
+	for _, value := range []optional{{}, {Count: &count, Name: &name, Created: &created}} {
+		fields, err := Fields{}.Encode(value)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+
+		decoded := optional{Count: new(int)}
+		err = Fields{}.Decode(fields, &decoded)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if !reflect.DeepEqual(decoded, value) {
+			t.Fatalf("expected %+v, got %+v", value, decoded)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package codec
+
+import (
+	"encoding/json"
+	"fmt"
+
+	errors_custom "github.com/enerBit/redsumer/v3/pkg/errors"
+)
+
+// ContentTypeJSON is the content type of the entries encoded by JSON.
+const ContentTypeJSON = "application/json"
+
+// JSON encodes values as JSON in FieldPayload.
+type JSON struct{}
+
+// Encode returns the fields of the entry holding v encoded as JSON.
+func (JSON) Encode(v any) (map[string]string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return EncodePayload(ContentTypeJSON, data), nil
+}
+
+// Decode decodes the JSON payload of an entry into v.
+func (JSON) Decode(fields map[string]string, v any) error {
+	data, err := DecodePayload(fields, ContentTypeJSON)
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(data, v)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errors_custom.ErrDecode, err)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package msgpack
+
+import (
+	"fmt"
+
+	"github.com/enerBit/redsumer/v3/pkg/codec"
+	errors_custom "github.com/enerBit/redsumer/v3/pkg/errors"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// ContentType is the content type of the entries encoded by Codec.
+const ContentType = "application/msgpack"
+
+// Codec encodes values as MessagePack in codec.FieldPayload.
+// Struct fields are named by their msgpack tag, as with github.com/vmihailenco/msgpack/v5.
+type Codec struct{}
+
+// Encode returns the fields of the entry holding v encoded as MessagePack.
+func (Codec) Encode(v any) (map[string]string, error) {
+	data, err := msgpack.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return codec.EncodePayload(ContentType, data), nil
+}
+
+// Decode decodes the MessagePack payload of an entry into v.
+func (Codec) Decode(fields map[string]string, v any) error {
+	data, err := codec.DecodePayload(fields, ContentType)
+	if err != nil {
+		return err
+	}
+
+	err = msgpack.Unmarshal(data, v)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errors_custom.ErrDecode, err)
+	}
+	return nil
+}
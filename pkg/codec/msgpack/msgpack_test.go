@@ -0,0 +1,50 @@
+package msgpack
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/enerBit/redsumer/v3/pkg/codec"
+	errors_custom "github.com/enerBit/redsumer/v3/pkg/errors"
+)
+
+type order struct {
+	ID     string  `msgpack:"id"`
+	Amount float64 `msgpack:"amount"`
+	Data   []byte  `msgpack:"data"`
+}
+
+func TestCodec(t *testing.T) {
+	value := order{ID: "1", Amount: 9.5, Data: []byte{0x00, 0xff, '\r', '\n'}}
+
+	fields, err := Codec{}.Encode(value)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if fields[codec.FieldContentType] != ContentType {
+		t.Fatalf("unexpected content type %s", fields[codec.FieldContentType])
+	}
+
+	var decoded order
+	err = Codec{}.Decode(fields, &decoded)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if !reflect.DeepEqual(decoded, value) {
+		t.Fatalf("expected %+v, got %+v", value, decoded)
+	}
+}
+
+func TestCodecErrors(t *testing.T) {
+	var decoded order
+	err := Codec{}.Decode(codec.EncodePayload(ContentType, []byte{0xc1}), &decoded)
+	if !errors.Is(err, errors_custom.ErrDecode) {
+		t.Fatalf("expected %v, got %v", errors_custom.ErrDecode, err)
+	}
+
+	err = Codec{}.Decode(codec.EncodePayload("application/json", []byte("{}")), &decoded)
+	if !errors.Is(err, errors_custom.ErrContentType) {
+		t.Fatalf("expected %v, got %v", errors_custom.ErrContentType, err)
+	}
+}
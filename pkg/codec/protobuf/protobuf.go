@@ -0,0 +1,71 @@
+package protobuf
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/enerBit/redsumer/v3/pkg/codec"
+	errors_custom "github.com/enerBit/redsumer/v3/pkg/errors"
+	"google.golang.org/protobuf/proto"
+)
+
+// ContentType is the content type of the entries encoded by Codec.
+const ContentType = "application/x-protobuf"
+
+// Codec encodes proto.Message values in codec.FieldPayload, in the binary wire format.
+// It decodes into a proto.Message, or into a pointer to a proto.Message pointer, allocating the message
+// when the pointer is nil, so it can be used with typed.Consumer[*pb.Message].
+type Codec struct {
+	MarshalOptions   proto.MarshalOptions
+	UnmarshalOptions proto.UnmarshalOptions
+}
+
+// Encode returns the fields of the entry holding the proto.Message v.
+func (c Codec) Encode(v any) (map[string]string, error) {
+	message, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("protobuf codec: cannot encode %T, expected a proto.Message", v)
+	}
+
+	data, err := c.MarshalOptions.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+	return codec.EncodePayload(ContentType, data), nil
+}
+
+// Decode decodes the protobuf payload of an entry into v.
+func (c Codec) Decode(fields map[string]string, v any) error {
+	message, ok := target(v)
+	if !ok {
+		return fmt.Errorf("protobuf codec: cannot decode into %T, expected a proto.Message", v)
+	}
+
+	data, err := codec.DecodePayload(fields, ContentType)
+	if err != nil {
+		return err
+	}
+
+	err = c.UnmarshalOptions.Unmarshal(data, message)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errors_custom.ErrDecode, err)
+	}
+	return nil
+}
+
+// target returns the proto.Message to decode into: v itself, or the message v points to.
+func target(v any) (proto.Message, bool) {
+	if message, ok := v.(proto.Message); ok {
+		return message, true
+	}
+
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Pointer || value.IsNil() || value.Elem().Kind() != reflect.Pointer {
+		return nil, false
+	}
+	if value.Elem().IsNil() {
+		value.Elem().Set(reflect.New(value.Elem().Type().Elem()))
+	}
+	message, ok := value.Elem().Interface().(proto.Message)
+	return message, ok
+}
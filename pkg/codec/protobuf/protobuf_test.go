@@ -0,0 +1,69 @@
+package protobuf
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/enerBit/redsumer/v3/pkg/codec"
+	errors_custom "github.com/enerBit/redsumer/v3/pkg/errors"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestCodec(t *testing.T) {
+	value := timestamppb.New(timestamppb.Now().AsTime())
+
+	fields, err := Codec{}.Encode(value)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if fields[codec.FieldContentType] != ContentType {
+		t.Fatalf("unexpected content type %s", fields[codec.FieldContentType])
+	}
+
+	var decoded *timestamppb.Timestamp
+	err = Codec{}.Decode(fields, &decoded)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if !proto.Equal(decoded, value) {
+		t.Fatalf("expected %v, got %v", value, decoded)
+	}
+}
+
+func TestCodecBinarySafe(t *testing.T) {
+	value := wrapperspb.Bytes([]byte{0x00, 0xff, '\r', '\n', 0x80})
+
+	fields, err := Codec{}.Encode(value)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	decoded := &wrapperspb.BytesValue{}
+	err = Codec{}.Decode(fields, decoded)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if !proto.Equal(decoded, value) {
+		t.Fatalf("expected %v, got %v", value, decoded)
+	}
+}
+
+func TestCodecErrors(t *testing.T) {
+	_, err := Codec{}.Encode("value")
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+
+	var decoded *timestamppb.Timestamp
+	err = Codec{}.Decode(codec.EncodePayload(ContentType, []byte{0xff}), &decoded)
+	if !errors.Is(err, errors_custom.ErrDecode) {
+		t.Fatalf("expected %v, got %v", errors_custom.ErrDecode, err)
+	}
+
+	err = Codec{}.Decode(codec.EncodePayload("application/json", []byte("{}")), &decoded)
+	if !errors.Is(err, errors_custom.ErrContentType) {
+		t.Fatalf("expected %v, got %v", errors_custom.ErrContentType, err)
+	}
+}
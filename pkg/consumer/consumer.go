@@ -3,13 +3,20 @@ package consumer
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/enerBit/redsumer/v3/pkg/client"
 	errors_custom "github.com/enerBit/redsumer/v3/pkg/errors"
+	"github.com/enerBit/redsumer/v3/pkg/tracing"
 	"github.com/valkey-io/valkey-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 
@@ -17,14 +24,52 @@ const (
 	consumer_NEVER_DELIVERED_TO_OTHER_CONSUMERS_SO_FAR = ">"
 	consumer_INITIAL_STREAM_ID                         = "0-0"
 	consumer_NOGROUP                                   = "NOGROUP No such key"
+	consumer_MAX_SEQUENCE                              = "18446744073709551615"
 )
 
+const (
+	// GroupStartBeginning makes a new group deliver every entry already in the stream.
+	GroupStartBeginning = "0"
+	// GroupStartLatest makes a new group deliver only the entries added after its creation.
+	GroupStartLatest = "$"
+)
+
+// GroupStartAt returns a group start ID that makes a new group deliver the entries added at or after t.
+func GroupStartAt(t time.Time) string {
+	ms := t.UnixMilli()
+	if ms <= 0 {
+		return GroupStartBeginning
+	}
+	return strconv.FormatInt(ms-1, 10) + "-" + consumer_MAX_SEQUENCE
+}
+
+// Message is a stream entry tagged with the name of the stream it was read from.
+type Message struct {
+	valkey.XRangeEntry
+	Stream string
+}
+
 type Consumer struct {
 	Client *client.ClientArgs
 
+	// Tries is the number of seconds to wait between the checks for the streams to exist.
 	Tries []int
-
-	StreamName   string
+	// WaitSchedule is the delays between the checks for the streams to exist.
+	// When set, it is used instead of Tries. Backoff.Schedule builds one from a backoff policy.
+	WaitSchedule []time.Duration
+	// MkStream creates the streams along with the group, using XGROUP CREATE MKSTREAM,
+	// instead of waiting for a producer to create them.
+	MkStream bool
+	// GroupStartID is the ID after which a newly created group starts delivering entries:
+	// GroupStartBeginning, GroupStartLatest, a stream ID, or GroupStartAt for a point in time.
+	// When empty, the group delivers every entry already in the stream.
+	// It has no effect on groups that already exist.
+	GroupStartID string
+
+	StreamName string
+	// StreamNames are additional streams read by the consumer, along with StreamName,
+	// in a single XREADGROUP call. The group is created on every stream.
+	StreamNames  []string
 	GroupName    string
 	ConsumerName string
 
@@ -35,8 +80,64 @@ type Consumer struct {
 	IdleStillMine    int64
 	MinIdleAutoClaim int64
 
-	latestPendingMessageId string
-	nextIdAutoClaim        string
+	// Block is the maximum time NewMessages waits for new messages when there are none.
+	// When zero, NewMessages returns immediately, and Run pauses briefly after a round without messages.
+	// It should be shorter than MinIdleAutoClaim, since the pending and autoclaim phases
+	// only run after the wait for new messages ends.
+	Block time.Duration
+
+	// DeadLetterStream is the stream that receives messages delivered more than MaxDeliveries times.
+	// Dead-lettering is disabled when DeadLetterStream is empty or MaxDeliveries is zero.
+	DeadLetterStream string
+	MaxDeliveries    int64
+
+	// Retry schedules the redelivery of messages whose handler failed in Run with exponential backoff.
+	// When nil, failed messages stay pending until they are read again by the pending or autoclaim phases.
+	Retry *RetryPolicy
+
+	// Workers is the number of goroutines Run uses to process messages concurrently.
+	// Values lower than 1 are treated as 1.
+	Workers int
+	// MaxInFlight bounds the number of messages handed to workers and not yet acknowledged.
+	// Values lower than Workers are treated as Workers.
+	MaxInFlight int
+
+	// DeleteConsumerOnShutdown removes the consumer from the group when Shutdown completes,
+	// in the streams where it has no pending messages left.
+	DeleteConsumerOnShutdown bool
+
+	// HealthMaxReadAge makes Health report the consumer unhealthy when its last successful read
+	// of new messages is older than this. It should be longer than Block and than the time taken
+	// to process a batch. Zero disables the check.
+	HealthMaxReadAge time.Duration
+
+	// TracerProvider creates the spans around the XREADGROUP, XAUTOCLAIM and XACK calls,
+	// and around each handler call in Run. When nil, the global provider is used.
+	TracerProvider trace.TracerProvider
+	// Propagator extracts the trace context injected by the producer in the messages, so the
+	// handler spans link to the span that produced the message. When nil, the global propagator is used.
+	Propagator propagation.TextMapPropagator
+
+	// Metrics receives the events of the consumer, such as the messages consumed and acknowledged.
+	// Metrics are disabled when nil.
+	Metrics Metrics
+
+	backlog atomic.Bool
+	// lastRead is the time of the last successful read of new messages, in Unix nanoseconds.
+	lastRead atomic.Int64
+
+	cursorMu               sync.Mutex
+	latestPendingMessageId map[string]string
+	nextIdAutoClaim        map[string]string
+
+	inFlightMu sync.Mutex
+	inFlight   map[string]struct{}
+
+	lastErrorsMu sync.Mutex
+	lastErrors   map[string]recordedError
+
+	runMu   sync.Mutex
+	running *runState
 }
 
 
@@ -44,22 +145,109 @@ type Consumer struct {
 // If any error occurs during the process, it returns nil and the error.
 // Otherwise, it returns the created Consumer instance and nil error.
 func (c *Consumer) InitConsumer(ctx context.Context) error {
-	err := c.Client.InitClient(ctx)
+	err := c.checkSlots()
+	if err != nil {
+		return err
+	}
+
+	err = c.checkRetry()
+	if err != nil {
+		return err
+	}
+
+	err = c.Client.InitClient(ctx)
 	if err != nil {
 		return err
 	}
 
-	c.latestPendingMessageId = consumer_INITIAL_STREAM_ID
-	c.nextIdAutoClaim = consumer_INITIAL_STREAM_ID
+	c.cursorMu.Lock()
+	c.latestPendingMessageId = make(map[string]string)
+	c.nextIdAutoClaim = make(map[string]string)
+	c.cursorMu.Unlock()
 
 	err = c.initGroup(ctx)
 	if err != nil {
 		return err
 	}
+	c.lastRead.Store(time.Now().UnixNano())
 
 	return nil
 }
 
+// streams returns the streams read by the consumer: StreamName followed by StreamNames,
+// without empty names nor duplicates.
+func (c *Consumer) streams() []string {
+	streams := make([]string, 0, len(c.StreamNames)+1)
+	seen := make(map[string]struct{}, len(c.StreamNames)+1)
+	for _, stream := range append([]string{c.StreamName}, c.StreamNames...) {
+		if _, ok := seen[stream]; ok || stream == "" {
+			continue
+		}
+		seen[stream] = struct{}{}
+		streams = append(streams, stream)
+	}
+	return streams
+}
+
+// checkSlots ensures that the keys used together in a single command map to the same hash slot
+// when the client is connected to a Valkey Cluster: the streams read by XREADGROUP, and
+// DeadLetterStream, written in the same transaction as the acknowledgement in the source stream.
+// Use a common hash tag, such as {orders}, in the names of the streams to place them in the same slot.
+func (c *Consumer) checkSlots() error {
+	if !c.Client.Cluster {
+		return nil
+	}
+
+	keys := c.streams()
+	if c.DeadLetterStream != "" {
+		keys = append(keys, c.DeadLetterStream)
+	}
+	for _, key := range keys {
+		if client.HashSlot(key) != client.HashSlot(keys[0]) {
+			return fmt.Errorf("%w: %s and %s", errors_custom.ErrCrossSlot, keys[0], key)
+		}
+	}
+
+	return nil
+}
+
+// cursor returns the cursor of a stream, or the initial stream ID if it has none yet.
+func cursor(cursors map[string]string, stream string) string {
+	if id, ok := cursors[stream]; ok {
+		return id
+	}
+	return consumer_INITIAL_STREAM_ID
+}
+
+// setCursor stores the cursor of a stream.
+func setCursor(cursors *map[string]string, stream string, id string) {
+	if *cursors == nil {
+		*cursors = make(map[string]string)
+	}
+	(*cursors)[stream] = id
+}
+
+// tag converts the entries read from a stream into messages tagged with the stream name.
+func tag(stream string, entries []valkey.XRangeEntry) []Message {
+	messages := make([]Message, 0, len(entries))
+	for _, entry := range entries {
+		messages = append(messages, Message{XRangeEntry: entry, Stream: stream})
+	}
+	return messages
+}
+
+// untag returns the entries of messages, dropping the stream they were read from.
+func untag(messages []Message) []valkey.XRangeEntry {
+	if messages == nil {
+		return nil
+	}
+	entries := make([]valkey.XRangeEntry, 0, len(messages))
+	for _, message := range messages {
+		entries = append(entries, message.XRangeEntry)
+	}
+	return entries
+}
+
 // exist checks if a key exists in the Valkey client.
 // It returns an error if the key does not exist.
 func (c *Consumer) exist(ctx context.Context, key string) error {
@@ -76,8 +264,8 @@ func (c *Consumer) exist(ctx context.Context, key string) error {
 	return nil
 }
 
-// createGroup creates a consumer group for processing messages from a stream.
-// It waits for the stream to be available and then creates the group using the provided arguments.
+// createGroup creates a consumer group for processing messages from every stream of the consumer.
+// It waits for each stream to be available, or creates it if MkStream is set, and then creates the group using the provided arguments.
 // If the group already exists, it returns without an error.
 // If any error occurs during the process, it is returned.
 func (c *Consumer) initGroup(ctx context.Context) error {
@@ -86,33 +274,87 @@ func (c *Consumer) initGroup(ctx context.Context) error {
 		return err
 	}
 
-	cmd := c.Client.Instance.B().XgroupCreate().Key(c.StreamName).Group(c.GroupName).Id(consumer_INITIAL_STREAM_ID).Build()
-	err = c.Client.Instance.Do(ctx, cmd).Error()
-	if err != nil {
-		var errV *valkey.ValkeyError
-		if errors.As(err, &errV) {
-			if errV.IsBusyGroup() {
-				return nil
-			} else {
-				return errV
+	for _, stream := range c.streams() {
+		create := c.Client.Instance.B().XgroupCreate().Key(stream).Group(c.GroupName).Id(c.groupStartID())
+
+		var cmd valkey.Completed
+		if c.MkStream {
+			cmd = create.Mkstream().Build()
+		} else {
+			cmd = create.Build()
+		}
+		err = c.Client.Instance.Do(ctx, cmd).Error()
+		if err != nil {
+			var errV *valkey.ValkeyError
+			if errors.As(err, &errV) {
+				if errV.IsBusyGroup() {
+					continue
+				} else {
+					return errV
+				}
 			}
+			return err
 		}
-		return err
 	}
 
 	return nil
 }
 
-// waitForStream waits for the stream to be ready by checking its existence in the Valkey client.
+// groupStartID returns the ID a new group starts from, GroupStartID or the initial stream ID.
+func (c *Consumer) groupStartID() string {
+	if c.GroupStartID != "" {
+		return c.GroupStartID
+	}
+	return consumer_INITIAL_STREAM_ID
+}
+
+// waitForStream waits for the streams to be ready by checking their existence in the Valkey client.
 // It retries for the specified number of times with a delay between each attempt.
-// If the stream is ready, it returns nil. Otherwise, it returns an error.
+// If every stream is ready, or MkStream is set, it returns nil. Otherwise, it returns an error.
 func (c *Consumer) waitForStream(ctx context.Context) error {
-	for _, waitTime := range c.Tries {
-		err := c.exist(ctx, c.StreamName)
+	if c.MkStream {
+		return nil
+	}
+
+	for _, stream := range c.streams() {
+		err := c.waitForKey(ctx, stream)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// waitSchedule returns the delays between the checks for the streams to exist,
+// taken from WaitSchedule or, when it is empty, from Tries.
+func (c *Consumer) waitSchedule() []time.Duration {
+	if len(c.WaitSchedule) != 0 {
+		return c.WaitSchedule
+	}
+
+	schedule := make([]time.Duration, len(c.Tries))
+	for i, waitTime := range c.Tries {
+		schedule[i] = time.Second * time.Duration(waitTime)
+	}
+	return schedule
+}
+
+// waitForKey waits for a single stream to exist, following the wait schedule.
+// It stops waiting and returns the context error as soon as the context is done.
+func (c *Consumer) waitForKey(ctx context.Context, stream string) error {
+	for _, waitTime := range c.waitSchedule() {
+		err := c.exist(ctx, stream)
 		if err == nil {
 			return nil
 		}
-		time.Sleep(time.Second * time.Duration(waitTime))
+
+		timer := time.NewTimer(waitTime)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
 	}
 	return errors_custom.ErrStreamNotFound
 }
@@ -121,9 +363,14 @@ func (c *Consumer) waitForStream(ctx context.Context) error {
 // It returns true if the message is still pending, otherwise false.
 // If there is an error while checking, it returns the error.
 func (c *Consumer) StillMine(ctx context.Context, messageID string) (bool, error) {
+	return c.StillMineInStream(ctx, c.StreamName, messageID)
+}
+
+// StillMineInStream is like StillMine for a message read from the given stream.
+func (c *Consumer) StillMineInStream(ctx context.Context, stream string, messageID string) (bool, error) {
 	var isMine bool
 
-	cmd := c.Client.Instance.B().Xpending().Key(c.StreamName).Group(c.GroupName).Idle(c.IdleStillMine).Start(messageID).End(messageID).Count(1).Consumer(c.ConsumerName).Build()
+	cmd := c.Client.Instance.B().Xpending().Key(stream).Group(c.GroupName).Idle(c.IdleStillMine).Start(messageID).End(messageID).Count(1).Consumer(c.ConsumerName).Build()
 	v, err := c.Client.Instance.Do(ctx, cmd).ToArray()
 	if err != nil {
 		return isMine, err
@@ -139,24 +386,52 @@ func (c *Consumer) StillMine(ctx context.Context, messageID string) (bool, error
 // Ack acknowledges a message with the given message ID in the consumer group.
 // It returns an error if there was a problem acknowledging the message.
 func (c *Consumer) AcknowledgeMessage(ctx context.Context, messageID string) error {
-	cmd := c.Client.Instance.B().Xack().Key(c.StreamName).Group(c.GroupName).Id(messageID).Build()
+	return c.AcknowledgeMessageInStream(ctx, c.StreamName, messageID)
+}
+
+// AcknowledgeMessageInStream is like AcknowledgeMessage for a message read from the given stream.
+func (c *Consumer) AcknowledgeMessageInStream(ctx context.Context, stream string, messageID string) (err error) {
+	ctx, span := c.startSpan(ctx, "XACK", "settle", stream)
+	defer func() { tracing.End(span, err) }()
+	span.SetAttributes(tracing.AttributeMessageID.String(messageID))
+
+	cmd := c.Client.Instance.B().Xack().Key(stream).Group(c.GroupName).Id(messageID).Build()
 	v, err := c.Client.Instance.Do(ctx, cmd).AsBool()
-	if err != nil {
-		return err
+	if err == nil && !v {
+		err = errors_custom.ErrNoAckedMessage
 	}
-	if !v {
-		return errors_custom.ErrNoAckedMessage
+	if c.Metrics != nil {
+		c.Metrics.Acked(stream, c.GroupName, err)
 	}
-	return nil
+	return err
 }
 
-// newMessages retrieves new messages from a Valkey stream for the consumer.
-// It uses the AsXRead command to read messages from the specified stream,
+// NewMessagesInStreams retrieves new messages from the Valkey streams of the consumer.
+// It uses the AsXRead command to read messages from every stream in a single call,
 // using the consumer group and name provided in the Consumer struct.
-// The function returns a slice of Valkey.XRangeEntry, which contains the retrieved messages,
+// The function returns a slice of Message, which contains the retrieved messages tagged with their stream,
 // and an error if any occurred during the retrieval process.
-func (c *Consumer) NewMessages(ctx context.Context) ([]valkey.XRangeEntry, error) {
-	cmd := c.Client.Instance.B().Xreadgroup().Group(c.GroupName, c.ConsumerName).Count(c.BatchSizeNewMessage).Streams().Key(c.StreamName).Id(consumer_NEVER_DELIVERED_TO_OTHER_CONSUMERS_SO_FAR).Build()
+// If Block is set, the command waits up to Block for new messages, unless the previous
+// Consume call returned pending or claimed messages, in which case it returns immediately
+// so the remaining backlog is not delayed. Cancelling the context aborts the wait.
+func (c *Consumer) NewMessagesInStreams(ctx context.Context) (messages []Message, err error) {
+	streams := c.streams()
+	ctx, span := c.startSpan(ctx, "XREADGROUP", "receive", streams...)
+	defer func() { endReceive(span, messages, err) }()
+
+	ids := make([]string, len(streams))
+	for i := range ids {
+		ids[i] = consumer_NEVER_DELIVERED_TO_OTHER_CONSUMERS_SO_FAR
+	}
+
+	builder := c.Client.Instance.B().Xreadgroup().Group(c.GroupName, c.ConsumerName).Count(c.BatchSizeNewMessage)
+
+	var cmd valkey.Completed
+	if c.Block > 0 && !c.backlog.Load() {
+		cmd = builder.Block(max(c.Block.Milliseconds(), 1)).Streams().Key(streams...).Id(ids...).Build()
+	} else {
+		cmd = builder.Streams().Key(streams...).Id(ids...).Build()
+	}
 	v, err := c.Client.Instance.Do(ctx, cmd).AsXRead()
 	if err != nil {
 		var errV *valkey.ValkeyError
@@ -168,15 +443,40 @@ func (c *Consumer) NewMessages(ctx context.Context) ([]valkey.XRangeEntry, error
 			return nil, err
 		}
 	}
+	c.lastRead.Store(time.Now().UnixNano())
 
-	fields := v[c.StreamName]
-	return fields, nil
+	for _, stream := range streams {
+		messages = append(messages, tag(stream, v[stream])...)
+	}
+	return messages, nil
 }
 
-// pendingMessages retrieves pending messages from a Valkey stream.
-// It returns a slice of Valkey.XRangeEntry representing the pending messages and an error if any.
-func (c *Consumer) PendingMessages(ctx context.Context) ([]valkey.XRangeEntry, error) {
-	cmd := c.Client.Instance.B().Xreadgroup().Group(c.GroupName, c.ConsumerName).Count(*c.BatchSizePending).Streams().Key(c.StreamName).Id(c.latestPendingMessageId).Build()
+// NewMessages is like NewMessagesInStreams, but returns the entries without the stream they were read from.
+// It suits consumers of a single stream; when StreamNames is set, use NewMessagesInStreams instead.
+func (c *Consumer) NewMessages(ctx context.Context) ([]valkey.XRangeEntry, error) {
+	messages, err := c.NewMessagesInStreams(ctx)
+	return untag(messages), err
+}
+
+// PendingMessagesInStreams retrieves pending messages from the Valkey streams of the consumer,
+// following a separate cursor for each stream.
+// It returns a slice of Message representing the pending messages and an error if any.
+// Messages delivered more than MaxDeliveries times are moved to DeadLetterStream instead of being returned.
+// Concurrent calls are serialized, so the pending cursor is never read and updated by two callers at once.
+func (c *Consumer) PendingMessagesInStreams(ctx context.Context) (messages []Message, err error) {
+	c.cursorMu.Lock()
+	defer c.cursorMu.Unlock()
+
+	streams := c.streams()
+	ctx, span := c.startSpan(ctx, "XREADGROUP", "receive", streams...)
+	defer func() { endReceive(span, messages, err) }()
+
+	ids := make([]string, len(streams))
+	for i, stream := range streams {
+		ids[i] = cursor(c.latestPendingMessageId, stream)
+	}
+
+	cmd := c.Client.Instance.B().Xreadgroup().Group(c.GroupName, c.ConsumerName).Count(*c.BatchSizePending).Streams().Key(streams...).Id(ids...).Build()
 	v, err := c.Client.Instance.Do(ctx, cmd).AsXRead()
 	if err != nil {
 		var errV *valkey.ValkeyError
@@ -189,44 +489,103 @@ func (c *Consumer) PendingMessages(ctx context.Context) ([]valkey.XRangeEntry, e
 		}
 	}
 
-	fields := v[c.StreamName]
-	if len(fields) != 0 {
-		c.latestPendingMessageId = fields[len(fields)-1].ID
-	} else {
-		c.latestPendingMessageId = consumer_INITIAL_STREAM_ID
+	for _, stream := range streams {
+		fields := v[stream]
+		if len(fields) != 0 {
+			setCursor(&c.latestPendingMessageId, stream, fields[len(fields)-1].ID)
+		} else {
+			setCursor(&c.latestPendingMessageId, stream, consumer_INITIAL_STREAM_ID)
+		}
+
+		fields, err = c.deadLetterExceeded(ctx, stream, fields)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, tag(stream, fields)...)
 	}
-	return fields, nil
+	return messages, nil
+}
+
+// PendingMessages is like PendingMessagesInStreams, but returns the entries without the stream they were read from.
+// It suits consumers of a single stream; when StreamNames is set, use PendingMessagesInStreams instead.
+func (c *Consumer) PendingMessages(ctx context.Context) ([]valkey.XRangeEntry, error) {
+	messages, err := c.PendingMessagesInStreams(ctx)
+	return untag(messages), err
 }
 
-// claimedMessages returns a slice of claimed messages from the Valkey stream.
+// AutoClaimMessagesInStreams returns a slice of claimed messages from the Valkey streams of the consumer.
 // It uses the XAutoClaim method of the Valkey client to automatically claim messages
-// from the specified stream and group. The minimum idle duration to claim a message
+// from each stream and group, following a separate cursor for each stream. The minimum idle duration to claim a message
 // is determined by the MinDurationToClaim field of the ConsumerArgs struct.
 // The Start field specifies the ID of the first message to claim, and the Count field
 // determines the number of messages to claim in a batch. The Consumer field specifies
 // the name of the consumer. If an error occurs during the claiming process, it is returned.
 // If the error is not equal to the Valkey_NIL error, it is returned as is.
 // Otherwise, the claimed messages are returned along with a nil error.
-func (c *Consumer) AutoClaimMessages(ctx context.Context) ([]valkey.XRangeEntry, error) {
-	cmd := c.Client.Instance.B().Xautoclaim().Key(c.StreamName).Group(c.GroupName).Consumer(c.ConsumerName).MinIdleTime(strconv.FormatInt(c.MinIdleAutoClaim, 10)).Start(c.nextIdAutoClaim).Count(*c.BatchSizeAutoClaim).Build()
-	v, err := c.Client.Instance.Do(ctx, cmd).ToArray()
-	if err != nil {
-		return nil, err
-	}
+// Messages delivered more than MaxDeliveries times are moved to DeadLetterStream instead of being returned.
+// Concurrent calls are serialized, so the autoclaim cursor is never read and updated by two callers at once.
+func (c *Consumer) AutoClaimMessagesInStreams(ctx context.Context) (messages []Message, err error) {
+	c.cursorMu.Lock()
+	defer c.cursorMu.Unlock()
+
+	streams := c.streams()
+	ctx, span := c.startSpan(ctx, "XAUTOCLAIM", "receive", streams...)
+	defer func() { endReceive(span, messages, err) }()
+
+	for _, stream := range streams {
+		cmd := c.Client.Instance.B().Xautoclaim().Key(stream).Group(c.GroupName).Consumer(c.ConsumerName).MinIdleTime(strconv.FormatInt(c.MinIdleAutoClaim, 10)).Start(cursor(c.nextIdAutoClaim, stream)).Count(*c.BatchSizeAutoClaim).Build()
+		v, err := c.Client.Instance.Do(ctx, cmd).ToArray()
+		if err != nil {
+			return nil, err
+		}
 
-	nextMessage, err := v[0].ToString()
-	if err != nil {
-		return nil, err
+		nextMessage, err := v[0].ToString()
+		if err != nil {
+			return nil, err
+		}
+
+		setCursor(&c.nextIdAutoClaim, stream, nextMessage)
+
+		e, err := v[1].AsXRange()
+		if err != nil {
+			return nil, err
+		}
+
+		e, err = c.deadLetterExceeded(ctx, stream, e)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, tag(stream, e)...)
 	}
 
-	c.nextIdAutoClaim = nextMessage
+	return messages, nil
+}
 
-	e, err := v[1].AsXRange()
-	if err != nil {
-		return nil, err
+// AutoClaimMessages is like AutoClaimMessagesInStreams, but returns the entries without the stream they were claimed from.
+// It suits consumers of a single stream; when StreamNames is set, use AutoClaimMessagesInStreams instead.
+func (c *Consumer) AutoClaimMessages(ctx context.Context) ([]valkey.XRangeEntry, error) {
+	messages, err := c.AutoClaimMessagesInStreams(ctx)
+	return untag(messages), err
+}
+
+// startSpan starts the span of a call to Valkey on the streams of the consumer.
+// The destination is only set when the call involves a single stream.
+func (c *Consumer) startSpan(ctx context.Context, name string, operation string, streams ...string) (context.Context, trace.Span) {
+	attributes := []attribute.KeyValue{
+		tracing.System,
+		tracing.AttributeOperation.String(operation),
+		tracing.AttributeGroup.String(c.GroupName),
+	}
+	if len(streams) == 1 {
+		attributes = append(attributes, tracing.AttributeDestination.String(streams[0]))
 	}
+	return tracing.Start(ctx, c.TracerProvider, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attributes...))
+}
 
-	return e, nil
+// endReceive records the number of messages received on a span, and ends it.
+func endReceive(span trace.Span, messages []Message, err error) {
+	span.SetAttributes(tracing.AttributeMessageCount.Int(len(messages)))
+	tracing.End(span, err)
 }
 
 // validateError checks if the given error contains a specific error message and performs an action accordingly.
@@ -234,20 +593,28 @@ func (c *Consumer) AutoClaimMessages(ctx context.Context) ([]valkey.XRangeEntry,
 // Otherwise, it returns the original error.
 func (c *Consumer) validateError(ctx context.Context, err error) error {
 	if strings.Contains(err.Error(), consumer_NOGROUP) {
-		return c.initGroup(ctx)
+		err = c.initGroup(ctx)
+		if err == nil && c.Metrics != nil {
+			c.Metrics.GroupRecovered(c.GroupName)
+		}
+		return err
 	}
 	return err
 }
 
 
-// Consume consumes messages from Valkey.
+// ConsumeStreams consumes messages from the Valkey streams of the consumer, tagged with the stream they were read from.
 // It first tries to fetch new messages, then pending messages, and finally claimed messages.
 // If any messages are found, they are returned along with a nil error.
 // If no messages are found, it returns nil and nil error.
-func (c *Consumer) Consume(ctx context.Context) ([]valkey.XRangeEntry, error) {
+// With Block set, an idle call waits up to Block in the new messages phase before
+// checking pending and claimed messages.
+// While Run has messages in flight, the pending phase is skipped: reading the pending
+// entries again would deliver the messages being processed once more.
+func (c *Consumer) ConsumeStreams(ctx context.Context) ([]Message, error) {
 	retry:
-		var messages []valkey.XRangeEntry
-		messages, err := c.NewMessages(ctx)
+		var messages []Message
+		messages, err := c.NewMessagesInStreams(ctx)
 		if err != nil {
 			err = c.validateError(ctx, err)
 			if err == nil {
@@ -256,11 +623,12 @@ func (c *Consumer) Consume(ctx context.Context) ([]valkey.XRangeEntry, error) {
 			return nil, err
 		}
 		if len(messages) != 0 {
+			c.consumed(PhaseNew, messages)
 			return messages, nil
 		}
 	
-		if c.BatchSizePending != nil {
-			messages, err = c.PendingMessages(ctx)
+		if c.BatchSizePending != nil && !c.hasInFlight() {
+			messages, err = c.PendingMessagesInStreams(ctx)
 			if err != nil {
 				err = c.validateError(ctx, err)
 				if err == nil {
@@ -269,12 +637,14 @@ func (c *Consumer) Consume(ctx context.Context) ([]valkey.XRangeEntry, error) {
 				return nil, err
 			}
 			if len(messages) != 0 {
+				c.backlog.Store(true)
+				c.consumed(PhasePending, messages)
 				return messages, nil
 			}
 		}
 	
 		if c.BatchSizeAutoClaim != nil {
-			messages, err = c.AutoClaimMessages(ctx)
+			messages, err = c.AutoClaimMessagesInStreams(ctx)
 			if err != nil {
 				err = c.validateError(ctx, err)
 				if err == nil {
@@ -283,9 +653,19 @@ func (c *Consumer) Consume(ctx context.Context) ([]valkey.XRangeEntry, error) {
 				return nil, err
 			}
 			if len(messages) != 0 {
+				c.backlog.Store(true)
+				c.consumed(PhaseAutoClaim, messages)
 				return messages, nil
 			}
 		}
 	
+		c.backlog.Store(false)
 		return nil, nil
-	}
\ No newline at end of file
+	}
+
+// Consume is like ConsumeStreams, but returns the entries without the stream they were read from.
+// It suits consumers of a single stream; when StreamNames is set, use ConsumeStreams instead.
+func (c *Consumer) Consume(ctx context.Context) ([]valkey.XRangeEntry, error) {
+	messages, err := c.ConsumeStreams(ctx)
+	return untag(messages), err
+}
@@ -2,10 +2,13 @@ package consumer
 
 import (
 	"context"
+	"errors"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/enerBit/redsumer/v3/pkg/client"
+	errors_custom "github.com/enerBit/redsumer/v3/pkg/errors"
 	"github.com/valkey-io/valkey-go"
 	"github.com/valkey-io/valkey-go/mock"
 	"go.uber.org/mock/gomock"
@@ -364,12 +367,11 @@ func TestPendingMessagesSuccess(t *testing.T) {
 
 	var S int64 = 1
 	c := &Consumer{
-		Client:                 clientArg,
-		StreamName:             streamName,
-		GroupName:              groupName,
-		ConsumerName:           consumerName,
-		BatchSizePending:       &S,
-		latestPendingMessageId: consumer_INITIAL_STREAM_ID,
+		Client:           clientArg,
+		StreamName:       streamName,
+		GroupName:        groupName,
+		ConsumerName:     consumerName,
+		BatchSizePending: &S,
 	}
 
 	_, err := c.PendingMessages(ctx)
@@ -392,12 +394,11 @@ func TestPendingMessagesError(t *testing.T) {
 
 	var S int64 = 1
 	c := &Consumer{
-		Client:                 clientArg,
-		StreamName:             streamName,
-		GroupName:              groupName,
-		ConsumerName:           consumerName,
-		BatchSizePending:       &S,
-		latestPendingMessageId: consumer_INITIAL_STREAM_ID,
+		Client:           clientArg,
+		StreamName:       streamName,
+		GroupName:        groupName,
+		ConsumerName:     consumerName,
+		BatchSizePending: &S,
 	}
 
 	_, err := c.PendingMessages(ctx)
@@ -425,7 +426,6 @@ func TestAutoClaimedSuccess(t *testing.T) {
 		GroupName:          groupName,
 		ConsumerName:       consumerName,
 		MinIdleAutoClaim:   100,
-		nextIdAutoClaim:    consumer_INITIAL_STREAM_ID,
 		BatchSizeAutoClaim: &S,
 	}
 
@@ -453,7 +453,6 @@ func TestAutoClaimedError(t *testing.T) {
 		GroupName:          groupName,
 		ConsumerName:       consumerName,
 		MinIdleAutoClaim:   100,
-		nextIdAutoClaim:    consumer_INITIAL_STREAM_ID,
 		BatchSizeAutoClaim: &S,
 	}
 
@@ -462,3 +461,395 @@ func TestAutoClaimedError(t *testing.T) {
 		t.Fatalf("expected error, got nil")
 	}
 }
+
+func TestNewMessagesBlock(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	db := mock.NewClient(ctrl)
+
+	db.EXPECT().Do(ctx, mock.Match("XREADGROUP", "GROUP", groupName, consumerName, "COUNT", "1", "BLOCK", "100", "STREAMS", streamName, consumer_NEVER_DELIVERED_TO_OTHER_CONSUMERS_SO_FAR)).Return(mock.Result(mock.ValkeyNil()))
+	clientArg := &client.ClientArgs{
+		Instance: db,
+	}
+	c := &Consumer{
+		Client:              clientArg,
+		StreamName:          streamName,
+		GroupName:           groupName,
+		ConsumerName:        consumerName,
+		BatchSizeNewMessage: 1,
+		Block:               100 * time.Millisecond,
+	}
+
+	messages, err := c.NewMessages(ctx)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	if len(messages) != 0 {
+		t.Fatalf("expected no messages, got %d", len(messages))
+	}
+}
+
+func TestConsumeBlockSkippedWithBacklog(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	db := mock.NewClient(ctrl)
+
+	messageId := "1676389477-0"
+	pending := mock.Result(mock.ValkeyMap(map[string]valkey.ValkeyMessage{streamName: mock.ValkeyArray(mock.ValkeyArray(mock.ValkeyString(messageId), mock.ValkeyArray(mock.ValkeyString("key"), mock.ValkeyString("value"))))}))
+
+	gomock.InOrder(
+		db.EXPECT().Do(ctx, mock.Match("XREADGROUP", "GROUP", groupName, consumerName, "COUNT", "1", "BLOCK", "100", "STREAMS", streamName, consumer_NEVER_DELIVERED_TO_OTHER_CONSUMERS_SO_FAR)).Return(mock.Result(mock.ValkeyNil())),
+		db.EXPECT().Do(ctx, mock.Match("XREADGROUP", "GROUP", groupName, consumerName, "COUNT", "1", "STREAMS", streamName, consumer_INITIAL_STREAM_ID)).Return(pending),
+		db.EXPECT().Do(ctx, mock.Match("XREADGROUP", "GROUP", groupName, consumerName, "COUNT", "1", "STREAMS", streamName, consumer_NEVER_DELIVERED_TO_OTHER_CONSUMERS_SO_FAR)).Return(mock.Result(mock.ValkeyNil())),
+	)
+
+	clientArg := &client.ClientArgs{
+		Instance: db,
+	}
+	var S int64 = 1
+	c := &Consumer{
+		Client:              clientArg,
+		StreamName:          streamName,
+		GroupName:           groupName,
+		ConsumerName:        consumerName,
+		BatchSizeNewMessage: 1,
+		BatchSizePending:    &S,
+		Block:               100 * time.Millisecond,
+	}
+
+	messages, err := c.Consume(ctx)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if len(messages) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(messages))
+	}
+
+	_, err = c.NewMessages(ctx)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+}
+
+func TestStreams(t *testing.T) {
+	c := &Consumer{
+		StreamName:  streamName,
+		StreamNames: []string{"other-" + streamName, streamName, "", "other-" + streamName},
+	}
+
+	streams := c.streams()
+	if len(streams) != 2 || streams[0] != streamName || streams[1] != "other-"+streamName {
+		t.Fatalf("expected [%s other-%s], got %v", streamName, streamName, streams)
+	}
+}
+
+func TestCreateGroupMultipleStreams(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	db := mock.NewClient(ctrl)
+
+	otherStream := "other-" + streamName
+	for _, stream := range []string{streamName, otherStream} {
+		db.EXPECT().Do(ctx, mock.Match("EXISTS", stream)).Return(mock.Result(mock.ValkeyInt64(1)))
+		db.EXPECT().Do(ctx, mock.Match("XGROUP", "CREATE", stream, groupName, consumer_INITIAL_STREAM_ID)).Return(mock.ErrorResult(nil))
+	}
+
+	clientArg := &client.ClientArgs{
+		Instance: db,
+	}
+	c := &Consumer{
+		Client:       clientArg,
+		StreamName:   streamName,
+		StreamNames:  []string{otherStream},
+		GroupName:    groupName,
+		ConsumerName: consumerName,
+		Tries:        []int{1},
+	}
+
+	err := c.initGroup(ctx)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+}
+
+func TestNewMessagesMultipleStreams(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	db := mock.NewClient(ctrl)
+
+	otherStream := "other-" + streamName
+	entry := func(id string) valkey.ValkeyMessage {
+		return mock.ValkeyArray(mock.ValkeyString(id), mock.ValkeyArray(mock.ValkeyString("key"), mock.ValkeyString("value")))
+	}
+
+	db.EXPECT().Do(ctx, mock.Match("XREADGROUP", "GROUP", groupName, consumerName, "COUNT", "1", "STREAMS", streamName, otherStream, consumer_NEVER_DELIVERED_TO_OTHER_CONSUMERS_SO_FAR, consumer_NEVER_DELIVERED_TO_OTHER_CONSUMERS_SO_FAR)).Return(mock.Result(mock.ValkeyMap(map[string]valkey.ValkeyMessage{
+		streamName:  mock.ValkeyArray(entry("1676389477-0")),
+		otherStream: mock.ValkeyArray(entry("1676389477-1")),
+	})))
+	clientArg := &client.ClientArgs{
+		Instance: db,
+	}
+	c := &Consumer{
+		Client:              clientArg,
+		StreamName:          streamName,
+		StreamNames:         []string{otherStream},
+		GroupName:           groupName,
+		ConsumerName:        consumerName,
+		BatchSizeNewMessage: 1,
+	}
+
+	messages, err := c.NewMessagesInStreams(ctx)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	if len(messages) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(messages))
+	}
+	if messages[0].Stream != streamName || messages[0].ID != "1676389477-0" {
+		t.Fatalf("expected message 1676389477-0 from %s, got %s from %s", streamName, messages[0].ID, messages[0].Stream)
+	}
+	if messages[1].Stream != otherStream || messages[1].ID != "1676389477-1" {
+		t.Fatalf("expected message 1676389477-1 from %s, got %s from %s", otherStream, messages[1].ID, messages[1].Stream)
+	}
+}
+
+func TestPendingMessagesMultipleStreamsCursors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	db := mock.NewClient(ctrl)
+
+	otherStream := "other-" + streamName
+	messageId := "1676389477-0"
+
+	gomock.InOrder(
+		db.EXPECT().Do(ctx, mock.Match("XREADGROUP", "GROUP", groupName, consumerName, "COUNT", "1", "STREAMS", streamName, otherStream, consumer_INITIAL_STREAM_ID, consumer_INITIAL_STREAM_ID)).Return(mock.Result(mock.ValkeyMap(map[string]valkey.ValkeyMessage{
+			streamName:  mock.ValkeyArray(mock.ValkeyArray(mock.ValkeyString(messageId), mock.ValkeyArray(mock.ValkeyString("key"), mock.ValkeyString("value")))),
+			otherStream: mock.ValkeyArray(),
+		}))),
+		db.EXPECT().Do(ctx, mock.Match("XREADGROUP", "GROUP", groupName, consumerName, "COUNT", "1", "STREAMS", streamName, otherStream, messageId, consumer_INITIAL_STREAM_ID)).Return(mock.Result(mock.ValkeyNil())),
+	)
+	clientArg := &client.ClientArgs{
+		Instance: db,
+	}
+
+	var S int64 = 1
+	c := &Consumer{
+		Client:           clientArg,
+		StreamName:       streamName,
+		StreamNames:      []string{otherStream},
+		GroupName:        groupName,
+		ConsumerName:     consumerName,
+		BatchSizePending: &S,
+	}
+
+	messages, err := c.PendingMessagesInStreams(ctx)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if len(messages) != 1 || messages[0].Stream != streamName {
+		t.Fatalf("expected 1 message from %s, got %v", streamName, messages)
+	}
+
+	_, err = c.PendingMessagesInStreams(ctx)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+}
+
+func TestAutoClaimedMultipleStreams(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	db := mock.NewClient(ctrl)
+
+	otherStream := "other-" + streamName
+	messageId := "1676389477-0"
+	db.EXPECT().Do(ctx, mock.Match("XAUTOCLAIM", streamName, groupName, consumerName, "100", consumer_INITIAL_STREAM_ID, "COUNT", "1")).Return(mock.Result(mock.ValkeyArray(mock.ValkeyString(consumer_INITIAL_STREAM_ID), mock.ValkeyArray())))
+	db.EXPECT().Do(ctx, mock.Match("XAUTOCLAIM", otherStream, groupName, consumerName, "100", consumer_INITIAL_STREAM_ID, "COUNT", "1")).Return(mock.Result(mock.ValkeyArray(mock.ValkeyString(messageId), mock.ValkeyArray(mock.ValkeyArray(mock.ValkeyString(messageId), mock.ValkeyMap(make(map[string]valkey.ValkeyMessage)))))))
+	clientArg := &client.ClientArgs{
+		Instance: db,
+	}
+	var S int64 = 1
+	c := &Consumer{
+		Client:             clientArg,
+		StreamName:         streamName,
+		StreamNames:        []string{otherStream},
+		GroupName:          groupName,
+		ConsumerName:       consumerName,
+		MinIdleAutoClaim:   100,
+		BatchSizeAutoClaim: &S,
+	}
+
+	messages, err := c.AutoClaimMessagesInStreams(ctx)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if len(messages) != 1 || messages[0].Stream != otherStream {
+		t.Fatalf("expected 1 message from %s, got %v", otherStream, messages)
+	}
+
+	if cursor(c.nextIdAutoClaim, otherStream) != messageId || cursor(c.nextIdAutoClaim, streamName) != consumer_INITIAL_STREAM_ID {
+		t.Fatalf("expected separate autoclaim cursors, got %v", c.nextIdAutoClaim)
+	}
+}
+
+func TestWaitForStreamSchedule(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	db := mock.NewClient(ctrl)
+
+	db.EXPECT().Do(ctx, mock.Match("EXISTS", streamName)).Return(mock.Result(mock.ValkeyInt64(0)))
+	db.EXPECT().Do(ctx, mock.Match("EXISTS", streamName)).Return(mock.Result(mock.ValkeyInt64(1)))
+
+	clientArg := &client.ClientArgs{
+		Instance: db,
+	}
+	c := &Consumer{
+		Client:       clientArg,
+		StreamName:   streamName,
+		GroupName:    groupName,
+		ConsumerName: consumerName,
+		Tries:        []int{60, 60},
+		WaitSchedule: []time.Duration{time.Millisecond, time.Millisecond},
+	}
+
+	err := c.waitForStream(ctx)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+}
+
+func TestWaitForStreamContextCancelled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	db := mock.NewClient(ctrl)
+
+	db.EXPECT().Do(ctx, mock.Match("EXISTS", streamName)).Return(mock.Result(mock.ValkeyInt64(0)))
+
+	clientArg := &client.ClientArgs{
+		Instance: db,
+	}
+	c := &Consumer{
+		Client:       clientArg,
+		StreamName:   streamName,
+		GroupName:    groupName,
+		ConsumerName: consumerName,
+		Tries:        []int{60, 60},
+	}
+
+	err := c.waitForStream(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context deadline exceeded, got %v", err)
+	}
+}
+
+func TestCreateGroupMkStream(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	db := mock.NewClient(ctrl)
+
+	db.EXPECT().Do(ctx, mock.Match("XGROUP", "CREATE", streamName, groupName, consumer_INITIAL_STREAM_ID, "MKSTREAM")).Return(mock.ErrorResult(nil))
+
+	clientArg := &client.ClientArgs{
+		Instance: db,
+	}
+	c := &Consumer{
+		Client:       clientArg,
+		StreamName:   streamName,
+		GroupName:    groupName,
+		ConsumerName: consumerName,
+		MkStream:     true,
+	}
+
+	err := c.initGroup(ctx)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+}
+
+func TestCreateGroupStartID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	db := mock.NewClient(ctrl)
+
+	db.EXPECT().Do(ctx, mock.Match("XGROUP", "CREATE", streamName, groupName, GroupStartLatest, "MKSTREAM")).Return(mock.ErrorResult(nil))
+
+	clientArg := &client.ClientArgs{
+		Instance: db,
+	}
+	c := &Consumer{
+		Client:       clientArg,
+		StreamName:   streamName,
+		GroupName:    groupName,
+		ConsumerName: consumerName,
+		MkStream:     true,
+		GroupStartID: GroupStartLatest,
+	}
+
+	err := c.initGroup(ctx)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+}
+
+func TestGroupStartAt(t *testing.T) {
+	id := GroupStartAt(time.UnixMilli(1676389477000))
+	if id != "1676389476999-18446744073709551615" {
+		t.Fatalf("expected 1676389476999-18446744073709551615, got %s", id)
+	}
+
+	id = GroupStartAt(time.Time{})
+	if id != GroupStartBeginning {
+		t.Fatalf("expected %s, got %s", GroupStartBeginning, id)
+	}
+}
+
+func TestInitConsumerCrossSlot(t *testing.T) {
+	c := &Consumer{
+		Client:           &client.ClientArgs{Cluster: true},
+		StreamName:       "{orders}.created",
+		StreamNames:      []string{"{orders}.updated"},
+		GroupName:        groupName,
+		ConsumerName:     consumerName,
+		DeadLetterStream: "{orders}.dead",
+	}
+
+	err := c.checkSlots()
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	c.StreamNames = []string{"payments"}
+	err = c.InitConsumer(context.Background())
+	if !errors.Is(err, errors_custom.ErrCrossSlot) {
+		t.Fatalf("expected %v, got %v", errors_custom.ErrCrossSlot, err)
+	}
+
+	c.Client.Cluster = false
+	err = c.checkSlots()
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+}
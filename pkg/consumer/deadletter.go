@@ -0,0 +1,255 @@
+package consumer
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/valkey-io/valkey-go"
+)
+
+// Fields added to every entry written to the dead-letter stream, next to the original fields.
+const (
+	DeadLetterFieldOriginalStream = "redsumer-original-stream"
+	DeadLetterFieldOriginalID     = "redsumer-original-id"
+	DeadLetterFieldGroup          = "redsumer-group"
+	DeadLetterFieldConsumer       = "redsumer-consumer"
+	DeadLetterFieldDeliveries     = "redsumer-deliveries"
+	DeadLetterFieldLastError      = "redsumer-last-error"
+)
+
+// deadLetterEnabled reports whether messages exceeding MaxDeliveries are moved to DeadLetterStream.
+func (c *Consumer) deadLetterEnabled() bool {
+	return c.DeadLetterStream != "" && c.MaxDeliveries > 0
+}
+
+// DeadLetter moves a message to the dead-letter stream and acknowledges it in the source group.
+// Both commands run in a single MULTI/EXEC transaction, so the message is never lost nor duplicated.
+// The dead-letter entry keeps the original fields and adds the metadata fields with the original
+// stream and ID, the group, the consumer, the delivery count and the last handler error, if known.
+func (c *Consumer) DeadLetter(ctx context.Context, message Message, deliveries int64) error {
+	fields := make([]string, 0, len(message.FieldValues))
+	for k := range message.FieldValues {
+		fields = append(fields, k)
+	}
+	sort.Strings(fields)
+
+	xadd := c.Client.Instance.B().Xadd().Key(c.DeadLetterStream).Id("*").FieldValue()
+	for _, k := range fields {
+		xadd = xadd.FieldValue(k, message.FieldValues[k])
+	}
+	xadd = xadd.FieldValue(DeadLetterFieldOriginalStream, message.Stream).
+		FieldValue(DeadLetterFieldOriginalID, message.ID).
+		FieldValue(DeadLetterFieldGroup, c.GroupName).
+		FieldValue(DeadLetterFieldConsumer, c.ConsumerName).
+		FieldValue(DeadLetterFieldDeliveries, strconv.FormatInt(deliveries, 10))
+	if lastError, ok := c.lastError(message); ok {
+		xadd = xadd.FieldValue(DeadLetterFieldLastError, lastError)
+	}
+
+	results := c.Client.Instance.DoMulti(ctx,
+		c.Client.Instance.B().Multi().Build(),
+		xadd.Build(),
+		c.Client.Instance.B().Xack().Key(message.Stream).Group(c.GroupName).Id(message.ID).Build(),
+		c.Client.Instance.B().Exec().Build(),
+	)
+	for _, result := range results {
+		err := result.Error()
+		if err != nil {
+			return err
+		}
+	}
+
+	replies, err := results[len(results)-1].ToArray()
+	if err != nil {
+		return err
+	}
+	for _, reply := range replies {
+		err = reply.Error()
+		if err != nil {
+			return err
+		}
+	}
+
+	c.forgetError(message)
+	return nil
+}
+
+// pendingEntry is the owner and delivery count of a pending message, as reported by XPENDING.
+type pendingEntry struct {
+	consumer   string
+	deliveries int64
+}
+
+// pendingEntries returns the pending entries of the given messages of a stream, owned by the given
+// consumer, or by any consumer when empty. Each message is queried by its exact ID, in a single
+// pipelined round trip, so other pending entries never take the place of the messages.
+func (c *Consumer) pendingEntries(ctx context.Context, stream string, messages []valkey.XRangeEntry, consumer string) (map[string]pendingEntry, error) {
+	cmds := make([]valkey.Completed, len(messages))
+	for i, message := range messages {
+		count := c.Client.Instance.B().Xpending().Key(stream).Group(c.GroupName).Start(message.ID).End(message.ID).Count(1)
+		if consumer != "" {
+			cmds[i] = count.Consumer(consumer).Build()
+		} else {
+			cmds[i] = count.Build()
+		}
+	}
+
+	entries := make(map[string]pendingEntry, len(messages))
+	for _, result := range c.Client.Instance.DoMulti(ctx, cmds...) {
+		v, err := result.ToArray()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range v {
+			values, err := entry.ToArray()
+			if err != nil {
+				return nil, err
+			}
+			if len(values) != 4 {
+				continue
+			}
+
+			id, err := values[0].ToString()
+			if err != nil {
+				return nil, err
+			}
+			owner, err := values[1].ToString()
+			if err != nil {
+				return nil, err
+			}
+			deliveries, err := values[3].AsInt64()
+			if err != nil {
+				return nil, err
+			}
+			entries[id] = pendingEntry{consumer: owner, deliveries: deliveries}
+		}
+	}
+
+	return entries, nil
+}
+
+// deliveryCounts returns the delivery count of the given messages of a stream, as reported by XPENDING
+// for the messages owned by this consumer.
+func (c *Consumer) deliveryCounts(ctx context.Context, stream string, messages []valkey.XRangeEntry) (map[string]int64, error) {
+	entries, err := c.pendingEntries(ctx, stream, messages, c.ConsumerName)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(entries))
+	for id, entry := range entries {
+		counts[id] = entry.deliveries
+	}
+	return counts, nil
+}
+
+// deadLetterExceeded moves the messages of a stream delivered more than MaxDeliveries times to the
+// dead-letter stream and returns the remaining ones.
+// Messages currently being processed by Run are never dead-lettered.
+// If dead-lettering is disabled, the messages are returned unchanged.
+func (c *Consumer) deadLetterExceeded(ctx context.Context, stream string, messages []valkey.XRangeEntry) ([]valkey.XRangeEntry, error) {
+	if !c.deadLetterEnabled() || len(messages) == 0 {
+		return messages, nil
+	}
+
+	counts, err := c.deliveryCounts(ctx, stream, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	remaining := make([]valkey.XRangeEntry, 0, len(messages))
+	for _, message := range messages {
+		deliveries := counts[message.ID]
+		if deliveries <= c.MaxDeliveries || c.isInFlight(Message{XRangeEntry: message, Stream: stream}) {
+			remaining = append(remaining, message)
+			continue
+		}
+
+		err = c.DeadLetter(ctx, Message{XRangeEntry: message, Stream: stream}, deliveries)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return remaining, nil
+}
+
+// consumer_PRUNE_ERRORS_MIN_INTERVAL is the shortest interval between two prunes of the recorded errors.
+const consumer_PRUNE_ERRORS_MIN_INTERVAL = time.Second
+
+// recordedError is the last handler error of a message.
+type recordedError struct {
+	stream string
+	id     string
+	err    string
+}
+
+// recordError remembers the last handler error of a message, so it can be attached
+// to the message if it is dead-lettered later. Errors are only kept when DeadLetterStream is set.
+func (c *Consumer) recordError(message Message, err error) {
+	if c.DeadLetterStream == "" {
+		return
+	}
+
+	c.lastErrorsMu.Lock()
+	defer c.lastErrorsMu.Unlock()
+
+	if c.lastErrors == nil {
+		c.lastErrors = make(map[string]recordedError)
+	}
+	c.lastErrors[messageKey(message)] = recordedError{stream: message.Stream, id: message.ID, err: err.Error()}
+}
+
+// lastError returns the last handler error recorded for a message.
+func (c *Consumer) lastError(message Message) (string, bool) {
+	c.lastErrorsMu.Lock()
+	defer c.lastErrorsMu.Unlock()
+
+	recorded, ok := c.lastErrors[messageKey(message)]
+	return recorded.err, ok
+}
+
+// forgetError removes the last handler error recorded for a message.
+func (c *Consumer) forgetError(message Message) {
+	c.lastErrorsMu.Lock()
+	defer c.lastErrorsMu.Unlock()
+
+	delete(c.lastErrors, messageKey(message))
+}
+
+// pruneErrors removes the errors recorded for messages no longer pending for this consumer nor
+// parked on RetryConsumerName, such as the messages claimed and acknowledged by another consumer,
+// which this consumer will not dead-letter.
+func (c *Consumer) pruneErrors(ctx context.Context) error {
+	c.lastErrorsMu.Lock()
+	recorded := make(map[string][]valkey.XRangeEntry)
+	for _, r := range c.lastErrors {
+		recorded[r.stream] = append(recorded[r.stream], valkey.XRangeEntry{ID: r.id})
+	}
+	c.lastErrorsMu.Unlock()
+
+	for stream, messages := range recorded {
+		entries, err := c.pendingEntries(ctx, stream, messages, "")
+		if err != nil {
+			return err
+		}
+
+		for _, message := range messages {
+			entry, ok := entries[message.ID]
+			if !ok || (entry.consumer != c.ConsumerName && entry.consumer != RetryConsumerName) {
+				c.forgetError(Message{XRangeEntry: message, Stream: stream})
+			}
+		}
+	}
+
+	return nil
+}
+
+// pruneErrorsInterval returns the interval between two prunes of the recorded errors.
+// Messages can only be claimed by another consumer once idle for MinIdleAutoClaim.
+func (c *Consumer) pruneErrorsInterval() time.Duration {
+	return max(time.Duration(c.MinIdleAutoClaim)*time.Millisecond, consumer_PRUNE_ERRORS_MIN_INTERVAL)
+}
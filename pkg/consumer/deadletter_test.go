@@ -0,0 +1,294 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/enerBit/redsumer/v3/pkg/client"
+	"github.com/valkey-io/valkey-go"
+	"github.com/valkey-io/valkey-go/mock"
+	"go.uber.org/mock/gomock"
+)
+
+const deadLetterStream string = "stream-test-dead-letter"
+
+// xpendingEntry builds an entry of the extended XPENDING reply, owned by the consumer of the tests.
+func xpendingEntry(messageId string, deliveries int64) valkey.ValkeyMessage {
+	return xpendingEntryOf(messageId, consumerName, deliveries)
+}
+
+// xpendingEntryOf builds an entry of the extended XPENDING reply, owned by the given consumer.
+func xpendingEntryOf(messageId string, owner string, deliveries int64) valkey.ValkeyMessage {
+	return mock.ValkeyArray(mock.ValkeyString(messageId), mock.ValkeyString(owner), mock.ValkeyInt64(1000), mock.ValkeyInt64(deliveries))
+}
+
+func TestDeadLetterSuccess(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	db := mock.NewClient(ctrl)
+
+	messageId := "1676389477-0"
+
+	db.EXPECT().DoMulti(ctx,
+		mock.Match("MULTI"),
+		mock.Match("XADD", deadLetterStream, "*", "key", "value",
+			DeadLetterFieldOriginalStream, streamName,
+			DeadLetterFieldOriginalID, messageId,
+			DeadLetterFieldGroup, groupName,
+			DeadLetterFieldConsumer, consumerName,
+			DeadLetterFieldDeliveries, "4",
+			DeadLetterFieldLastError, "error"),
+		mock.Match("XACK", streamName, groupName, messageId),
+		mock.Match("EXEC"),
+	).Return([]valkey.ValkeyResult{
+		mock.Result(mock.ValkeyString("OK")),
+		mock.Result(mock.ValkeyString("QUEUED")),
+		mock.Result(mock.ValkeyString("QUEUED")),
+		mock.Result(mock.ValkeyArray(mock.ValkeyString("1676389478-0"), mock.ValkeyInt64(1))),
+	})
+
+	clientArg := &client.ClientArgs{
+		Instance: db,
+	}
+	c := &Consumer{
+		Client:           clientArg,
+		StreamName:       streamName,
+		GroupName:        groupName,
+		ConsumerName:     consumerName,
+		DeadLetterStream: deadLetterStream,
+		MaxDeliveries:    3,
+	}
+	c.recordError(Message{XRangeEntry: valkey.XRangeEntry{ID: messageId}, Stream: streamName}, errors.New("error"))
+
+	err := c.DeadLetter(ctx, Message{XRangeEntry: valkey.XRangeEntry{ID: messageId, FieldValues: map[string]string{"key": "value"}}, Stream: streamName}, 4)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	if _, ok := c.lastError(Message{XRangeEntry: valkey.XRangeEntry{ID: messageId}, Stream: streamName}); ok {
+		t.Fatalf("expected last error to be forgotten")
+	}
+}
+
+func TestDeadLetterError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	db := mock.NewClient(ctrl)
+
+	messageId := "1676389477-0"
+
+	db.EXPECT().DoMulti(ctx, gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return([]valkey.ValkeyResult{
+		mock.Result(mock.ValkeyString("OK")),
+		mock.Result(mock.ValkeyString("QUEUED")),
+		mock.Result(mock.ValkeyString("QUEUED")),
+		mock.Result(mock.ValkeyError("EXECABORT Transaction discarded because of previous errors.")),
+	})
+
+	clientArg := &client.ClientArgs{
+		Instance: db,
+	}
+	c := &Consumer{
+		Client:           clientArg,
+		StreamName:       streamName,
+		GroupName:        groupName,
+		ConsumerName:     consumerName,
+		DeadLetterStream: deadLetterStream,
+		MaxDeliveries:    3,
+	}
+
+	err := c.DeadLetter(ctx, Message{XRangeEntry: valkey.XRangeEntry{ID: messageId, FieldValues: map[string]string{"key": "value"}}, Stream: streamName}, 4)
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+}
+
+func TestAutoClaimedDeadLetterExceeded(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	db := mock.NewClient(ctrl)
+
+	poisonId := "1676389477-0"
+	messageId := "1676389477-1"
+	entry := func(id string) valkey.ValkeyMessage {
+		return mock.ValkeyArray(mock.ValkeyString(id), mock.ValkeyArray(mock.ValkeyString("key"), mock.ValkeyString("value")))
+	}
+
+	db.EXPECT().Do(ctx, mock.Match("XAUTOCLAIM", streamName, groupName, consumerName, "100", consumer_INITIAL_STREAM_ID, "COUNT", "2")).Return(mock.Result(mock.ValkeyArray(mock.ValkeyString(consumer_INITIAL_STREAM_ID), mock.ValkeyArray(entry(poisonId), entry(messageId)))))
+	db.EXPECT().DoMulti(ctx,
+		mock.Match("XPENDING", streamName, groupName, poisonId, poisonId, "1", consumerName),
+		mock.Match("XPENDING", streamName, groupName, messageId, messageId, "1", consumerName),
+	).Return([]valkey.ValkeyResult{
+		mock.Result(mock.ValkeyArray(xpendingEntry(poisonId, 4))),
+		mock.Result(mock.ValkeyArray(xpendingEntry(messageId, 2))),
+	})
+	db.EXPECT().DoMulti(ctx, mock.Match("MULTI"), gomock.Any(), mock.Match("XACK", streamName, groupName, poisonId), mock.Match("EXEC")).Return([]valkey.ValkeyResult{
+		mock.Result(mock.ValkeyString("OK")),
+		mock.Result(mock.ValkeyString("QUEUED")),
+		mock.Result(mock.ValkeyString("QUEUED")),
+		mock.Result(mock.ValkeyArray(mock.ValkeyString("1676389478-0"), mock.ValkeyInt64(1))),
+	})
+
+	clientArg := &client.ClientArgs{
+		Instance: db,
+	}
+	var S int64 = 2
+	c := &Consumer{
+		Client:             clientArg,
+		StreamName:         streamName,
+		GroupName:          groupName,
+		ConsumerName:       consumerName,
+		MinIdleAutoClaim:   100,
+		BatchSizeAutoClaim: &S,
+		DeadLetterStream:   deadLetterStream,
+		MaxDeliveries:      3,
+	}
+
+	messages, err := c.AutoClaimMessages(ctx)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	if len(messages) != 1 || messages[0].ID != messageId {
+		t.Fatalf("expected only message %s, got %v", messageId, messages)
+	}
+}
+
+func TestPruneErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	db := mock.NewClient(ctrl)
+
+	ownedId := "1676389477-0"
+	parkedId := "1676389477-1"
+	claimedId := "1676389477-2"
+	ackedId := "1676389477-3"
+
+	db.EXPECT().DoMulti(ctx, gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, cmds ...valkey.Completed) []valkey.ValkeyResult {
+		owners := map[string]string{ownedId: consumerName, parkedId: RetryConsumerName, claimedId: "consumer-other"}
+		results := make([]valkey.ValkeyResult, len(cmds))
+		for i, cmd := range cmds {
+			id := cmd.Commands()[3]
+			if owner, ok := owners[id]; ok {
+				results[i] = mock.Result(mock.ValkeyArray(xpendingEntryOf(id, owner, 2)))
+				continue
+			}
+			results[i] = mock.Result(mock.ValkeyArray())
+		}
+		return results
+	})
+
+	clientArg := &client.ClientArgs{
+		Instance: db,
+	}
+	c := &Consumer{
+		Client:           clientArg,
+		StreamName:       streamName,
+		GroupName:        groupName,
+		ConsumerName:     consumerName,
+		DeadLetterStream: deadLetterStream,
+		MaxDeliveries:    3,
+	}
+	for _, id := range []string{ownedId, parkedId, claimedId, ackedId} {
+		c.recordError(Message{XRangeEntry: valkey.XRangeEntry{ID: id}, Stream: streamName}, errors.New("error"))
+	}
+
+	err := c.pruneErrors(ctx)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	for id, kept := range map[string]bool{ownedId: true, parkedId: true, claimedId: false, ackedId: false} {
+		_, ok := c.lastError(Message{XRangeEntry: valkey.XRangeEntry{ID: id}, Stream: streamName})
+		if ok != kept {
+			t.Errorf("expected last error of %s to be kept: %v, got %v", id, kept, ok)
+		}
+	}
+}
+
+func TestRetriedDeadLetterLastError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	db := mock.NewClient(ctrl)
+
+	messageId := "1676389477-0"
+	entry := mock.ValkeyArray(mock.ValkeyString(messageId), mock.ValkeyArray(mock.ValkeyString("key"), mock.ValkeyString("value")))
+
+	gomock.InOrder(
+		// the handler fails and the message is parked on the retry consumer
+		db.EXPECT().Do(gomock.Any(), mock.Match("XPENDING", streamName, groupName, "IDLE", "0", messageId, messageId, "1", consumerName)).Return(mock.Result(mock.ValkeyArray(valkey.ValkeyMessage{}))),
+		db.EXPECT().DoMulti(gomock.Any(), mock.Match("XPENDING", streamName, groupName, messageId, messageId, "1", consumerName)).Return([]valkey.ValkeyResult{mock.Result(mock.ValkeyArray(xpendingEntry(messageId, 3)))}),
+		db.EXPECT().Do(gomock.Any(), mock.Match("XCLAIM", streamName, groupName, RetryConsumerName, "0", messageId, "IDLE", "200", "JUSTID")).Return(mock.Result(mock.ValkeyArray(mock.ValkeyString(messageId)))),
+		// the error survives the prune while the message waits on the retry consumer
+		db.EXPECT().DoMulti(gomock.Any(), mock.Match("XPENDING", streamName, groupName, messageId, messageId, "1")).Return([]valkey.ValkeyResult{mock.Result(mock.ValkeyArray(xpendingEntryOf(messageId, RetryConsumerName, 3)))}),
+		// the message is claimed back past MaxDeliveries and dead-lettered with its last error
+		db.EXPECT().Do(gomock.Any(), mock.Match("XAUTOCLAIM", streamName, groupName, consumerName, "1000", consumer_INITIAL_STREAM_ID, "COUNT", "1")).Return(mock.Result(mock.ValkeyArray(mock.ValkeyString(consumer_INITIAL_STREAM_ID), mock.ValkeyArray(entry)))),
+		db.EXPECT().DoMulti(gomock.Any(), mock.Match("XPENDING", streamName, groupName, messageId, messageId, "1", consumerName)).Return([]valkey.ValkeyResult{mock.Result(mock.ValkeyArray(xpendingEntry(messageId, 4)))}),
+		db.EXPECT().DoMulti(gomock.Any(),
+			mock.Match("MULTI"),
+			mock.Match("XADD", deadLetterStream, "*", "key", "value",
+				DeadLetterFieldOriginalStream, streamName,
+				DeadLetterFieldOriginalID, messageId,
+				DeadLetterFieldGroup, groupName,
+				DeadLetterFieldConsumer, consumerName,
+				DeadLetterFieldDeliveries, "4",
+				DeadLetterFieldLastError, "failed"),
+			mock.Match("XACK", streamName, groupName, messageId),
+			mock.Match("EXEC"),
+		).Return([]valkey.ValkeyResult{
+			mock.Result(mock.ValkeyString("OK")),
+			mock.Result(mock.ValkeyString("QUEUED")),
+			mock.Result(mock.ValkeyString("QUEUED")),
+			mock.Result(mock.ValkeyArray(mock.ValkeyString("1676389478-0"), mock.ValkeyInt64(1))),
+		}),
+	)
+
+	clientArg := &client.ClientArgs{
+		Instance: db,
+	}
+	var S int64 = 1
+	c := &Consumer{
+		Client:             clientArg,
+		StreamName:         streamName,
+		GroupName:          groupName,
+		ConsumerName:       consumerName,
+		MinIdleAutoClaim:   1000,
+		BatchSizeAutoClaim: &S,
+		DeadLetterStream:   deadLetterStream,
+		MaxDeliveries:      3,
+		Retry: &RetryPolicy{
+			Backoff: Backoff{InitialInterval: 200 * time.Millisecond, Multiplier: 2},
+		},
+	}
+
+	err := c.process(ctx, func(ctx context.Context, message Message) error {
+		return errors.New("failed")
+	}, Message{XRangeEntry: valkey.XRangeEntry{ID: messageId, FieldValues: map[string]string{"key": "value"}}, Stream: streamName})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	err = c.pruneErrors(ctx)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	messages, err := c.AutoClaimMessages(ctx)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if len(messages) != 0 {
+		t.Fatalf("expected message %s to be dead-lettered, got %v", messageId, messages)
+	}
+}
@@ -0,0 +1,163 @@
+package consumer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/enerBit/redsumer/v3/pkg/client"
+	errors_custom "github.com/enerBit/redsumer/v3/pkg/errors"
+)
+
+// ConfigFromEnv returns a Consumer configured from the environment variables starting with prefix:
+//
+//	URL                     connection URL, parsed by client.FromURL
+//	STREAM_NAME             required
+//	STREAM_NAMES            comma-separated additional streams
+//	GROUP_NAME              required
+//	CONSUMER_NAME           required
+//	BATCH_SIZE_NEW_MESSAGE  required, greater than zero
+//	BATCH_SIZE_PENDING      pending phase batch size, disabled when unset
+//	BATCH_SIZE_AUTOCLAIM    autoclaim phase batch size, disabled when unset
+//	IDLE_STILL_MINE         milliseconds, or a duration such as 30s
+//	MIN_IDLE_AUTOCLAIM      milliseconds, or a duration such as 5m
+//	TRIES                   comma-separated seconds between the checks for the streams to exist
+//	BLOCK                   duration such as 2s
+//
+// With the prefix ORDERS_, the stream name is read from ORDERS_STREAM_NAME.
+// Every invalid or missing variable is reported in the returned error, which wraps ErrInvalidConfig.
+// The client is left nil when URL is unset.
+func ConfigFromEnv(prefix string) (*Consumer, error) {
+	env := envReader{prefix: prefix}
+	c := &Consumer{
+		StreamName:          env.required("STREAM_NAME"),
+		StreamNames:         env.list("STREAM_NAMES"),
+		GroupName:           env.required("GROUP_NAME"),
+		ConsumerName:        env.required("CONSUMER_NAME"),
+		BatchSizeNewMessage: env.positive("BATCH_SIZE_NEW_MESSAGE"),
+		BatchSizePending:    env.optionalPositive("BATCH_SIZE_PENDING"),
+		BatchSizeAutoClaim:  env.optionalPositive("BATCH_SIZE_AUTOCLAIM"),
+		IdleStillMine:       env.millis("IDLE_STILL_MINE"),
+		MinIdleAutoClaim:    env.millis("MIN_IDLE_AUTOCLAIM"),
+		Tries:               env.ints("TRIES"),
+		Block:               env.duration("BLOCK"),
+	}
+
+	if url, ok := env.lookup("URL"); ok {
+		args, err := client.FromURL(url)
+		if err != nil {
+			env.fail("URL", err)
+		}
+		c.Client = args
+	}
+
+	if len(env.errs) != 0 {
+		return nil, errors.Join(env.errs...)
+	}
+	return c, nil
+}
+
+// envReader reads prefixed environment variables and collects their errors.
+type envReader struct {
+	prefix string
+	errs   []error
+}
+
+func (e *envReader) lookup(name string) (string, bool) {
+	value, ok := os.LookupEnv(e.prefix + name)
+	return strings.TrimSpace(value), ok && strings.TrimSpace(value) != ""
+}
+
+func (e *envReader) fail(name string, err error) {
+	if !errors.Is(err, errors_custom.ErrInvalidConfig) {
+		err = fmt.Errorf("%w: %w", errors_custom.ErrInvalidConfig, err)
+	}
+	e.errs = append(e.errs, fmt.Errorf("%s%s: %w", e.prefix, name, err))
+}
+
+func (e *envReader) required(name string) string {
+	value, ok := e.lookup(name)
+	if !ok {
+		e.fail(name, errors.New("must be set"))
+	}
+	return value
+}
+
+func (e *envReader) list(name string) []string {
+	value, ok := e.lookup(name)
+	if !ok {
+		return nil
+	}
+
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func (e *envReader) positive(name string) int64 {
+	value, ok := e.lookup(name)
+	if !ok {
+		e.fail(name, errors.New("must be set"))
+		return 0
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n <= 0 {
+		e.fail(name, fmt.Errorf("must be a positive integer, got %q", value))
+	}
+	return n
+}
+
+func (e *envReader) optionalPositive(name string) *int64 {
+	if _, ok := e.lookup(name); !ok {
+		return nil
+	}
+	n := e.positive(name)
+	return &n
+}
+
+func (e *envReader) millis(name string) int64 {
+	value, ok := e.lookup(name)
+	if !ok {
+		return 0
+	}
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil && n >= 0 {
+		return n
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		e.fail(name, fmt.Errorf("must be milliseconds or a duration, got %q", value))
+	}
+	return d.Milliseconds()
+}
+
+func (e *envReader) duration(name string) time.Duration {
+	value, ok := e.lookup(name)
+	if !ok {
+		return 0
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		e.fail(name, fmt.Errorf("must be a duration, got %q", value))
+	}
+	return d
+}
+
+func (e *envReader) ints(name string) []int {
+	var ints []int
+	for _, item := range e.list(name) {
+		n, err := strconv.Atoi(item)
+		if err != nil || n < 0 {
+			e.fail(name, fmt.Errorf("must be comma-separated non-negative integers, got %q", item))
+			continue
+		}
+		ints = append(ints, n)
+	}
+	return ints
+}
@@ -0,0 +1,67 @@
+package consumer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	errors_custom "github.com/enerBit/redsumer/v3/pkg/errors"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("ORDERS_URL", "valkey://localhost:6379/1")
+	t.Setenv("ORDERS_STREAM_NAME", streamName)
+	t.Setenv("ORDERS_STREAM_NAMES", "stream-a, stream-b")
+	t.Setenv("ORDERS_GROUP_NAME", groupName)
+	t.Setenv("ORDERS_CONSUMER_NAME", consumerName)
+	t.Setenv("ORDERS_BATCH_SIZE_NEW_MESSAGE", "10")
+	t.Setenv("ORDERS_BATCH_SIZE_AUTOCLAIM", "5")
+	t.Setenv("ORDERS_IDLE_STILL_MINE", "30s")
+	t.Setenv("ORDERS_MIN_IDLE_AUTOCLAIM", "60000")
+	t.Setenv("ORDERS_TRIES", "1,2,5")
+	t.Setenv("ORDERS_BLOCK", "2s")
+
+	c, err := ConfigFromEnv("ORDERS_")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	if c.Client == nil || c.Client.Host != "localhost" || c.Client.DB != 1 {
+		t.Fatalf("unexpected client %+v", c.Client)
+	}
+	if c.StreamName != streamName || c.GroupName != groupName || c.ConsumerName != consumerName {
+		t.Fatalf("unexpected names %s %s %s", c.StreamName, c.GroupName, c.ConsumerName)
+	}
+	if strings.Join(c.StreamNames, ",") != "stream-a,stream-b" {
+		t.Fatalf("unexpected stream names %v", c.StreamNames)
+	}
+	if c.BatchSizeNewMessage != 10 || c.BatchSizePending != nil || c.BatchSizeAutoClaim == nil || *c.BatchSizeAutoClaim != 5 {
+		t.Fatalf("unexpected batch sizes")
+	}
+	if c.IdleStillMine != 30000 || c.MinIdleAutoClaim != 60000 {
+		t.Fatalf("unexpected idle times %d %d", c.IdleStillMine, c.MinIdleAutoClaim)
+	}
+	if len(c.Tries) != 3 || c.Tries[2] != 5 || c.Block != 2*time.Second {
+		t.Fatalf("unexpected tries %v or block %s", c.Tries, c.Block)
+	}
+}
+
+func TestConfigFromEnvInvalid(t *testing.T) {
+	t.Setenv("ORDERS_STREAM_NAME", streamName)
+	t.Setenv("ORDERS_BATCH_SIZE_NEW_MESSAGE", "0")
+	t.Setenv("ORDERS_BATCH_SIZE_PENDING", "many")
+	t.Setenv("ORDERS_TRIES", "1,x")
+	t.Setenv("ORDERS_URL", "http://localhost")
+
+	_, err := ConfigFromEnv("ORDERS_")
+	if !errors.Is(err, errors_custom.ErrInvalidConfig) {
+		t.Fatalf("expected %v, got %v", errors_custom.ErrInvalidConfig, err)
+	}
+
+	for _, name := range []string{"ORDERS_GROUP_NAME", "ORDERS_CONSUMER_NAME", "ORDERS_BATCH_SIZE_NEW_MESSAGE", "ORDERS_BATCH_SIZE_PENDING", "ORDERS_TRIES", "ORDERS_URL"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("expected error for %s, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	errors_custom "github.com/enerBit/redsumer/v3/pkg/errors"
+)
+
+// Health checks that the consumer can work: Valkey answers PING, every stream exists and has the group,
+// and, when HealthMaxReadAge is set, new messages were read successfully recently.
+// It returns the first failed check, wrapping ErrStreamNotFound, ErrGroupNotFound or ErrNoRecentRead.
+func (c *Consumer) Health(ctx context.Context) error {
+	err := c.Client.Instance.Do(ctx, c.Client.Instance.B().Ping().Build()).Error()
+	if err != nil {
+		return err
+	}
+
+	for _, stream := range c.streams() {
+		err = c.exist(ctx, stream)
+		if err == errors_custom.ErrKeyNotFound {
+			return fmt.Errorf("%w: %s", errors_custom.ErrStreamNotFound, stream)
+		}
+		if err != nil {
+			return err
+		}
+
+		err = c.groupExists(ctx, stream)
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.HealthMaxReadAge > 0 {
+		age := time.Since(time.Unix(0, c.lastRead.Load()))
+		if c.lastRead.Load() == 0 || age > c.HealthMaxReadAge {
+			return fmt.Errorf("%w: last read %s ago", errors_custom.ErrNoRecentRead, age.Round(time.Millisecond))
+		}
+	}
+
+	return nil
+}
+
+// groupExists checks that the group of the consumer exists in a stream.
+func (c *Consumer) groupExists(ctx context.Context, stream string) error {
+	_, err := c.groupInfo(ctx, stream)
+	return err
+}
@@ -0,0 +1,78 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/enerBit/redsumer/v3/pkg/client"
+	errors_custom "github.com/enerBit/redsumer/v3/pkg/errors"
+	"github.com/valkey-io/valkey-go"
+	"github.com/valkey-io/valkey-go/mock"
+	"go.uber.org/mock/gomock"
+)
+
+// xinfoGroup builds an entry of the XINFO GROUPS reply.
+func xinfoGroup(name string) valkey.ValkeyMessage {
+	return mock.ValkeyMap(map[string]valkey.ValkeyMessage{
+		"name":      mock.ValkeyString(name),
+		"consumers": mock.ValkeyInt64(1),
+	})
+}
+
+func TestHealth(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	db := mock.NewClient(ctrl)
+
+	db.EXPECT().Do(ctx, mock.Match("PING")).Return(mock.Result(mock.ValkeyString("PONG")))
+	db.EXPECT().Do(ctx, mock.Match("EXISTS", streamName)).Return(mock.Result(mock.ValkeyInt64(1)))
+	db.EXPECT().Do(ctx, mock.Match("XINFO", "GROUPS", streamName)).Return(mock.Result(mock.ValkeyArray(xinfoGroup("other"), xinfoGroup(groupName))))
+
+	c := &Consumer{
+		Client:           &client.ClientArgs{Instance: db},
+		StreamName:       streamName,
+		GroupName:        groupName,
+		ConsumerName:     consumerName,
+		HealthMaxReadAge: time.Minute,
+	}
+	c.lastRead.Store(time.Now().UnixNano())
+
+	err := c.Health(ctx)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+}
+
+func TestHealthFailures(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	db := mock.NewClient(ctrl)
+
+	db.EXPECT().Do(ctx, mock.Match("PING")).Return(mock.Result(mock.ValkeyString("PONG"))).Times(3)
+	db.EXPECT().Do(ctx, mock.Match("EXISTS", streamName)).Return(mock.Result(mock.ValkeyInt64(0)))
+	db.EXPECT().Do(ctx, mock.Match("EXISTS", streamName)).Return(mock.Result(mock.ValkeyInt64(1))).Times(2)
+	db.EXPECT().Do(ctx, mock.Match("XINFO", "GROUPS", streamName)).Return(mock.Result(mock.ValkeyArray(xinfoGroup("other"))))
+	db.EXPECT().Do(ctx, mock.Match("XINFO", "GROUPS", streamName)).Return(mock.Result(mock.ValkeyArray(xinfoGroup(groupName))))
+
+	c := &Consumer{
+		Client:           &client.ClientArgs{Instance: db},
+		StreamName:       streamName,
+		GroupName:        groupName,
+		ConsumerName:     consumerName,
+		HealthMaxReadAge: time.Minute,
+	}
+	c.lastRead.Store(time.Now().Add(-time.Hour).UnixNano())
+
+	for _, expected := range []error{errors_custom.ErrStreamNotFound, errors_custom.ErrGroupNotFound, errors_custom.ErrNoRecentRead} {
+		err := c.Health(ctx)
+		if !errors.Is(err, expected) {
+			t.Fatalf("expected %v, got %v", expected, err)
+		}
+	}
+}
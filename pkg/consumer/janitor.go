@@ -0,0 +1,195 @@
+package consumer
+
+import (
+	"context"
+	"time"
+)
+
+// consumer_JANITOR_CLAIM_BATCH is the number of pending messages claimed at once from a stale consumer.
+const consumer_JANITOR_CLAIM_BATCH = 100
+
+// groupConsumer is an entry of the XINFO CONSUMERS reply.
+type groupConsumer struct {
+	name    string
+	pending int64
+	idle    time.Duration
+}
+
+// hasPending reports whether a consumer has pending messages in a stream.
+func (c *Consumer) hasPending(ctx context.Context, stream string, consumerName string) (bool, error) {
+	ids, err := c.pendingIds(ctx, stream, consumerName, 1)
+	if err != nil {
+		return false, err
+	}
+	return len(ids) != 0, nil
+}
+
+// pendingIds returns up to count IDs of the messages pending for a consumer in a stream.
+func (c *Consumer) pendingIds(ctx context.Context, stream string, consumerName string, count int64) ([]string, error) {
+	cmd := c.Client.Instance.B().Xpending().Key(stream).Group(c.GroupName).Start("-").End("+").Count(count).Consumer(consumerName).Build()
+	v, err := c.Client.Instance.Do(ctx, cmd).ToArray()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(v))
+	for _, entry := range v {
+		values, err := entry.ToArray()
+		if err != nil {
+			return nil, err
+		}
+		if len(values) == 0 {
+			continue
+		}
+
+		id, err := values[0].ToString()
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// deleteConsumer removes a consumer from the group of a stream.
+func (c *Consumer) deleteConsumer(ctx context.Context, stream string, consumerName string) error {
+	cmd := c.Client.Instance.B().XgroupDelconsumer().Key(stream).Group(c.GroupName).Consumername(consumerName).Build()
+	return c.Client.Instance.Do(ctx, cmd).Error()
+}
+
+// DeleteConsumer removes this consumer from the group of every stream it has no pending messages in.
+// Streams where it still owns pending messages keep it, so those messages can be claimed by
+// other consumers and the consumer reaped later by ReapConsumers.
+func (c *Consumer) DeleteConsumer(ctx context.Context) error {
+	for _, stream := range c.streams() {
+		pending, err := c.hasPending(ctx, stream, c.ConsumerName)
+		if err != nil {
+			return err
+		}
+		if pending {
+			continue
+		}
+
+		err = c.deleteConsumer(ctx, stream, c.ConsumerName)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// groupConsumers returns the consumers of the group in a stream.
+func (c *Consumer) groupConsumers(ctx context.Context, stream string) ([]groupConsumer, error) {
+	cmd := c.Client.Instance.B().XinfoConsumers().Key(stream).Group(c.GroupName).Build()
+	v, err := c.Client.Instance.Do(ctx, cmd).ToArray()
+	if err != nil {
+		return nil, err
+	}
+
+	consumers := make([]groupConsumer, 0, len(v))
+	for _, entry := range v {
+		info, err := entry.AsMap()
+		if err != nil {
+			return nil, err
+		}
+
+		name := info["name"]
+		consumer := groupConsumer{}
+		consumer.name, err = name.ToString()
+		if err != nil {
+			return nil, err
+		}
+		pending := info["pending"]
+		consumer.pending, err = pending.AsInt64()
+		if err != nil {
+			return nil, err
+		}
+		idle := info["idle"]
+		ms, err := idle.AsInt64()
+		if err != nil {
+			return nil, err
+		}
+		consumer.idle = time.Duration(ms) * time.Millisecond
+
+		consumers = append(consumers, consumer)
+	}
+
+	return consumers, nil
+}
+
+// claimAll moves every message pending for another consumer of a stream to this consumer.
+// Delivery counts are left unchanged, and the messages are delivered again by the pending phase.
+func (c *Consumer) claimAll(ctx context.Context, stream string, consumerName string) error {
+	for {
+		ids, err := c.pendingIds(ctx, stream, consumerName, consumer_JANITOR_CLAIM_BATCH)
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		cmd := c.Client.Instance.B().Xclaim().Key(stream).Group(c.GroupName).Consumer(c.ConsumerName).MinIdleTime("0").Id(ids...).Justid().Build()
+		err = c.Client.Instance.Do(ctx, cmd).Error()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// ReapConsumers removes from the group the consumers idle for longer than maxIdle, in every stream.
+// Their pending messages are first claimed by this consumer, so none of them is lost.
+// This consumer and RetryConsumerName are never removed.
+func (c *Consumer) ReapConsumers(ctx context.Context, maxIdle time.Duration) error {
+	for _, stream := range c.streams() {
+		consumers, err := c.groupConsumers(ctx, stream)
+		if err != nil {
+			return err
+		}
+
+		for _, consumer := range consumers {
+			if consumer.name == c.ConsumerName || consumer.name == RetryConsumerName || consumer.idle <= maxIdle {
+				continue
+			}
+
+			if consumer.pending != 0 {
+				err = c.claimAll(ctx, stream, consumer.name)
+				if err != nil {
+					return err
+				}
+			}
+
+			err = c.deleteConsumer(ctx, stream, consumer.name)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// RunJanitor calls ReapConsumers every interval until the context is cancelled.
+// It returns nil when the context is cancelled, or the first error returned by ReapConsumers.
+func (c *Consumer) RunJanitor(ctx context.Context, interval time.Duration, maxIdle time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		err := c.ReapConsumers(ctx, maxIdle)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+	}
+}
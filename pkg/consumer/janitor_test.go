@@ -0,0 +1,127 @@
+package consumer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/enerBit/redsumer/v3/pkg/client"
+	"github.com/valkey-io/valkey-go"
+	"github.com/valkey-io/valkey-go/mock"
+	"go.uber.org/mock/gomock"
+)
+
+// xinfoConsumer builds an entry of the XINFO CONSUMERS reply.
+func xinfoConsumer(name string, pending int64, idle int64) valkey.ValkeyMessage {
+	return mock.ValkeyMap(map[string]valkey.ValkeyMessage{
+		"name":    mock.ValkeyString(name),
+		"pending": mock.ValkeyInt64(pending),
+		"idle":    mock.ValkeyInt64(idle),
+	})
+}
+
+func TestShutdownDeletesConsumer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	db := mock.NewClient(ctrl)
+
+	db.EXPECT().Do(ctx, mock.Match("XPENDING", streamName, groupName, "-", "+", "1", consumerName)).Return(mock.Result(mock.ValkeyArray()))
+	db.EXPECT().Do(ctx, mock.Match("XGROUP", "DELCONSUMER", streamName, groupName, consumerName)).Return(mock.Result(mock.ValkeyInt64(0)))
+
+	clientArg := &client.ClientArgs{
+		Instance: db,
+	}
+	c := &Consumer{
+		Client:                   clientArg,
+		StreamName:               streamName,
+		GroupName:                groupName,
+		ConsumerName:             consumerName,
+		DeleteConsumerOnShutdown: true,
+	}
+
+	err := c.Shutdown(ctx)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+}
+
+func TestDeleteConsumerWithPending(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	db := mock.NewClient(ctrl)
+
+	messageId := "1676389477-0"
+	db.EXPECT().Do(ctx, mock.Match("XPENDING", streamName, groupName, "-", "+", "1", consumerName)).Return(mock.Result(mock.ValkeyArray(xpendingEntry(messageId, 1))))
+
+	clientArg := &client.ClientArgs{
+		Instance: db,
+	}
+	c := &Consumer{
+		Client:       clientArg,
+		StreamName:   streamName,
+		GroupName:    groupName,
+		ConsumerName: consumerName,
+	}
+
+	err := c.DeleteConsumer(ctx)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+}
+
+func TestReapConsumers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	db := mock.NewClient(ctrl)
+
+	staleConsumer := "consumer-stale"
+	idleConsumer := "consumer-idle"
+	messageId := "1676389477-0"
+
+	db.EXPECT().Do(ctx, mock.Match("XINFO", "CONSUMERS", streamName, groupName)).Return(mock.Result(mock.ValkeyArray(
+		xinfoConsumer(consumerName, 0, 120000),
+		xinfoConsumer(RetryConsumerName, 1, 120000),
+		xinfoConsumer(staleConsumer, 1, 120000),
+		xinfoConsumer(idleConsumer, 0, 120000),
+		xinfoConsumer("consumer-alive", 3, 10),
+	)))
+	gomock.InOrder(
+		db.EXPECT().Do(ctx, mock.Match("XPENDING", streamName, groupName, "-", "+", "100", staleConsumer)).Return(mock.Result(mock.ValkeyArray(xpendingEntry(messageId, 1)))),
+		db.EXPECT().Do(ctx, mock.Match("XCLAIM", streamName, groupName, consumerName, "0", messageId, "JUSTID")).Return(mock.Result(mock.ValkeyArray(mock.ValkeyString(messageId)))),
+		db.EXPECT().Do(ctx, mock.Match("XPENDING", streamName, groupName, "-", "+", "100", staleConsumer)).Return(mock.Result(mock.ValkeyArray())),
+		db.EXPECT().Do(ctx, mock.Match("XGROUP", "DELCONSUMER", streamName, groupName, staleConsumer)).Return(mock.Result(mock.ValkeyInt64(0))),
+		db.EXPECT().Do(ctx, mock.Match("XGROUP", "DELCONSUMER", streamName, groupName, idleConsumer)).Return(mock.Result(mock.ValkeyInt64(0))),
+	)
+
+	clientArg := &client.ClientArgs{
+		Instance: db,
+	}
+	c := &Consumer{
+		Client:       clientArg,
+		StreamName:   streamName,
+		GroupName:    groupName,
+		ConsumerName: consumerName,
+	}
+
+	err := c.ReapConsumers(ctx, time.Minute)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+}
+
+func TestRunJanitorContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	c := &Consumer{}
+	err := c.RunJanitor(ctx, time.Minute, time.Minute)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	errors_custom "github.com/enerBit/redsumer/v3/pkg/errors"
+	"github.com/valkey-io/valkey-go"
+)
+
+// Phases of Consume a message can be read in.
+const (
+	PhaseNew       = "new"
+	PhasePending   = "pending"
+	PhaseAutoClaim = "autoclaim"
+)
+
+// Metrics receives the events of a consumer, to record them as metrics.
+// Its methods are called from the goroutines of Run and must be safe for concurrent use.
+// metrics.Collector implements it for Prometheus.
+type Metrics interface {
+	// Consumed is called when Consume returns count messages read from a stream in a phase.
+	Consumed(stream string, group string, phase string, count int)
+	// Handled is called when the handler called by Run returns, with its duration and error.
+	Handled(stream string, group string, duration time.Duration, err error)
+	// Acked is called after each XACK, with a nil error or the reason the message was not acknowledged,
+	// such as ErrNoAckedMessage.
+	Acked(stream string, group string, err error)
+	// GroupRecovered is called when the group was created again after Valkey answered NOGROUP.
+	GroupRecovered(group string)
+}
+
+// consumed reports the messages returned by a phase of Consume to Metrics, counted per stream.
+func (c *Consumer) consumed(phase string, messages []Message) {
+	if c.Metrics == nil || len(messages) == 0 {
+		return
+	}
+
+	counts := make(map[string]int)
+	for _, message := range messages {
+		counts[message.Stream]++
+	}
+	for stream, count := range counts {
+		c.Metrics.Consumed(stream, c.GroupName, phase, count)
+	}
+}
+
+// Lag returns the number of entries of each stream not yet delivered to the group, as reported
+// by XINFO GROUPS. Streams whose lag Valkey cannot compute, such as after entries were deleted,
+// are left out, as are all the streams on servers older than Redis 7.0.
+// It fails with ErrGroupNotFound when a stream does not have the group.
+func (c *Consumer) Lag(ctx context.Context) (map[string]int64, error) {
+	lag := make(map[string]int64)
+	for _, stream := range c.streams() {
+		info, err := c.groupInfo(ctx, stream)
+		if err != nil {
+			return nil, err
+		}
+
+		field, ok := info["lag"]
+		if !ok {
+			continue
+		}
+		n, err := field.AsInt64()
+		if valkey.IsValkeyNil(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		lag[stream] = n
+	}
+	return lag, nil
+}
+
+// groupInfo returns the XINFO GROUPS entry of the group of the consumer in a stream.
+// It fails with ErrGroupNotFound when the stream does not have the group.
+func (c *Consumer) groupInfo(ctx context.Context, stream string) (map[string]valkey.ValkeyMessage, error) {
+	cmd := c.Client.Instance.B().XinfoGroups().Key(stream).Build()
+	v, err := c.Client.Instance.Do(ctx, cmd).ToArray()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range v {
+		info, err := entry.AsMap()
+		if err != nil {
+			return nil, err
+		}
+		name := info["name"]
+		group, err := name.ToString()
+		if err != nil {
+			return nil, err
+		}
+		if group == c.GroupName {
+			return info, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s in %s", errors_custom.ErrGroupNotFound, c.GroupName, stream)
+}
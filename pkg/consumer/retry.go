@@ -0,0 +1,127 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	errors_custom "github.com/enerBit/redsumer/v3/pkg/errors"
+	"github.com/valkey-io/valkey-go"
+)
+
+// RetryConsumerName is the consumer that owns failed messages while they wait for redelivery.
+// Parking them there keeps them out of the pending phase of the consumer that failed them,
+// until the autoclaim phase of any consumer claims them back.
+const RetryConsumerName = "redsumer-retry"
+
+// Backoff computes exponentially growing delays with optional jitter.
+type Backoff struct {
+	// InitialInterval is the delay of the first attempt.
+	InitialInterval time.Duration
+	// MaxInterval caps the delay of any attempt. Zero means no cap.
+	MaxInterval time.Duration
+	// Multiplier is the factor applied to the delay after every attempt.
+	// Values lower than 1 are treated as 2.
+	Multiplier float64
+	// Jitter is the fraction of the delay randomized in both directions, between 0 and 1.
+	Jitter float64
+}
+
+// Duration returns the delay of the given attempt, starting at 1.
+func (b Backoff) Duration(attempt int) time.Duration {
+	multiplier := b.Multiplier
+	if multiplier < 1 {
+		multiplier = 2
+	}
+
+	delay := float64(b.InitialInterval) * math.Pow(multiplier, float64(max(attempt, 1)-1))
+	if b.MaxInterval > 0 && delay > float64(b.MaxInterval) {
+		delay = float64(b.MaxInterval)
+	}
+
+	if b.Jitter > 0 {
+		delay += delay * min(b.Jitter, 1) * (2*rand.Float64() - 1)
+	}
+
+	return time.Duration(delay)
+}
+
+// Schedule returns the delays of the first n attempts.
+func (b Backoff) Schedule(n int) []time.Duration {
+	schedule := make([]time.Duration, n)
+	for i := range schedule {
+		schedule[i] = b.Duration(i + 1)
+	}
+	return schedule
+}
+
+// RetryPolicy schedules the redelivery of messages whose handler failed.
+// A failed message is parked on RetryConsumerName with its idle time set so the autoclaim
+// phase claims it back once its backoff delay has elapsed. Delays therefore saturate at
+// MinIdleAutoClaim: InitConsumer fails with ErrInvalidConfig when MaxInterval exceeds it, and
+// a Backoff without MaxInterval stops growing at MinIdleAutoClaim.
+// InitConsumer also fails with ErrInvalidConfig unless the consumer sets BatchSizeAutoClaim and
+// a positive MinIdleAutoClaim, since the failed messages would otherwise never be claimed back,
+// or claimed back without any delay.
+type RetryPolicy struct {
+	Backoff
+
+	// MaxAttempts is the maximum number of deliveries of a message. A message that fails on its
+	// last attempt is moved to DeadLetterStream if set, or left to the autoclaim phase otherwise.
+	// Zero means no limit.
+	MaxAttempts int64
+}
+
+// checkRetry ensures that the backoff delays of Retry can be honoured, since the failed messages
+// are claimed back by the autoclaim phase and their delays are bounded by MinIdleAutoClaim.
+func (c *Consumer) checkRetry() error {
+	if c.Retry == nil {
+		return nil
+	}
+
+	if c.BatchSizeAutoClaim == nil {
+		return fmt.Errorf("%w: Retry requires BatchSizeAutoClaim", errors_custom.ErrInvalidConfig)
+	}
+	if c.MinIdleAutoClaim <= 0 {
+		return fmt.Errorf("%w: Retry requires a positive MinIdleAutoClaim", errors_custom.ErrInvalidConfig)
+	}
+
+	bound := time.Duration(c.MinIdleAutoClaim) * time.Millisecond
+	if c.Retry.MaxInterval > bound {
+		return fmt.Errorf("%w: Retry.MaxInterval %s exceeds MinIdleAutoClaim %s", errors_custom.ErrInvalidConfig, c.Retry.MaxInterval, bound)
+	}
+	return nil
+}
+
+// scheduleRetry parks a failed message on RetryConsumerName until its backoff delay elapses.
+// If the message has no attempts left, it is dead-lettered instead when dead-lettering is enabled.
+// It does nothing if Retry is not set.
+func (c *Consumer) scheduleRetry(ctx context.Context, message Message) error {
+	if c.Retry == nil {
+		return nil
+	}
+
+	counts, err := c.deliveryCounts(ctx, message.Stream, []valkey.XRangeEntry{message.XRangeEntry})
+	if err != nil {
+		return err
+	}
+	deliveries, ok := counts[message.ID]
+	if !ok {
+		return nil
+	}
+
+	if c.Retry.MaxAttempts > 0 && deliveries >= c.Retry.MaxAttempts {
+		if c.DeadLetterStream != "" {
+			return c.DeadLetter(ctx, message, deliveries)
+		}
+		return nil
+	}
+
+	delay := min(c.Retry.Duration(int(deliveries)).Milliseconds(), c.MinIdleAutoClaim)
+	idle := max(c.MinIdleAutoClaim-delay, 0)
+
+	cmd := c.Client.Instance.B().Xclaim().Key(message.Stream).Group(c.GroupName).Consumer(RetryConsumerName).MinIdleTime("0").Id(message.ID).Idle(idle).Justid().Build()
+	return c.Client.Instance.Do(ctx, cmd).Error()
+}
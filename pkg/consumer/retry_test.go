@@ -0,0 +1,229 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/enerBit/redsumer/v3/pkg/client"
+	errors_custom "github.com/enerBit/redsumer/v3/pkg/errors"
+	"github.com/valkey-io/valkey-go"
+	"github.com/valkey-io/valkey-go/mock"
+	"go.uber.org/mock/gomock"
+)
+
+func TestBackoffDuration(t *testing.T) {
+	b := Backoff{
+		InitialInterval: 100 * time.Millisecond,
+		MaxInterval:     time.Second,
+		Multiplier:      2,
+	}
+
+	expected := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second}
+	for i, want := range expected {
+		got := b.Duration(i + 1)
+		if got != want {
+			t.Fatalf("attempt %d: expected %v, got %v", i+1, want, got)
+		}
+	}
+}
+
+func TestBackoffSchedule(t *testing.T) {
+	b := Backoff{
+		InitialInterval: 100 * time.Millisecond,
+		Multiplier:      3,
+	}
+
+	schedule := b.Schedule(3)
+	expected := []time.Duration{100 * time.Millisecond, 300 * time.Millisecond, 900 * time.Millisecond}
+	if len(schedule) != len(expected) {
+		t.Fatalf("expected %d delays, got %d", len(expected), len(schedule))
+	}
+	for i, want := range expected {
+		if schedule[i] != want {
+			t.Fatalf("attempt %d: expected %v, got %v", i+1, want, schedule[i])
+		}
+	}
+}
+
+func TestBackoffDurationJitter(t *testing.T) {
+	b := Backoff{
+		InitialInterval: 100 * time.Millisecond,
+		Jitter:          0.5,
+	}
+
+	for i := 0; i < 100; i++ {
+		got := b.Duration(1)
+		if got < 50*time.Millisecond || got > 150*time.Millisecond {
+			t.Fatalf("expected delay between 50ms and 150ms, got %v", got)
+		}
+	}
+}
+
+func TestScheduleRetrySuccess(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	db := mock.NewClient(ctrl)
+
+	messageId := "1676389477-0"
+
+	db.EXPECT().DoMulti(ctx, mock.Match("XPENDING", streamName, groupName, messageId, messageId, "1", consumerName)).Return([]valkey.ValkeyResult{mock.Result(mock.ValkeyArray(xpendingEntry(messageId, 2)))})
+	db.EXPECT().Do(ctx, mock.Match("XCLAIM", streamName, groupName, RetryConsumerName, "0", messageId, "IDLE", "800", "JUSTID")).Return(mock.Result(mock.ValkeyArray(mock.ValkeyString(messageId))))
+
+	clientArg := &client.ClientArgs{
+		Instance: db,
+	}
+	c := &Consumer{
+		Client:           clientArg,
+		StreamName:       streamName,
+		GroupName:        groupName,
+		ConsumerName:     consumerName,
+		MinIdleAutoClaim: 1000,
+		Retry: &RetryPolicy{
+			Backoff:     Backoff{InitialInterval: 100 * time.Millisecond, Multiplier: 2},
+			MaxAttempts: 3,
+		},
+	}
+
+	err := c.scheduleRetry(ctx, Message{XRangeEntry: valkey.XRangeEntry{ID: messageId}, Stream: streamName})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+}
+
+func TestScheduleRetryCappedByMinIdleAutoClaim(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	db := mock.NewClient(ctrl)
+
+	messageId := "1676389477-0"
+
+	db.EXPECT().DoMulti(ctx, mock.Match("XPENDING", streamName, groupName, messageId, messageId, "1", consumerName)).Return([]valkey.ValkeyResult{mock.Result(mock.ValkeyArray(xpendingEntry(messageId, 1)))})
+	db.EXPECT().Do(ctx, mock.Match("XCLAIM", streamName, groupName, RetryConsumerName, "0", messageId, "IDLE", "0", "JUSTID")).Return(mock.Result(mock.ValkeyArray(mock.ValkeyString(messageId))))
+
+	clientArg := &client.ClientArgs{
+		Instance: db,
+	}
+	c := &Consumer{
+		Client:           clientArg,
+		StreamName:       streamName,
+		GroupName:        groupName,
+		ConsumerName:     consumerName,
+		MinIdleAutoClaim: 1000,
+		Retry: &RetryPolicy{
+			Backoff: Backoff{InitialInterval: time.Minute},
+		},
+	}
+
+	err := c.scheduleRetry(ctx, Message{XRangeEntry: valkey.XRangeEntry{ID: messageId}, Stream: streamName})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+}
+
+func TestScheduleRetryExhausted(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	db := mock.NewClient(ctrl)
+
+	messageId := "1676389477-0"
+
+	db.EXPECT().DoMulti(ctx, mock.Match("XPENDING", streamName, groupName, messageId, messageId, "1", consumerName)).Return([]valkey.ValkeyResult{mock.Result(mock.ValkeyArray(xpendingEntry(messageId, 3)))})
+	db.EXPECT().DoMulti(ctx, mock.Match("MULTI"), gomock.Any(), mock.Match("XACK", streamName, groupName, messageId), mock.Match("EXEC")).Return([]valkey.ValkeyResult{
+		mock.Result(mock.ValkeyString("OK")),
+		mock.Result(mock.ValkeyString("QUEUED")),
+		mock.Result(mock.ValkeyString("QUEUED")),
+		mock.Result(mock.ValkeyArray(mock.ValkeyString("1676389478-0"), mock.ValkeyInt64(1))),
+	})
+
+	clientArg := &client.ClientArgs{
+		Instance: db,
+	}
+	c := &Consumer{
+		Client:           clientArg,
+		StreamName:       streamName,
+		GroupName:        groupName,
+		ConsumerName:     consumerName,
+		MinIdleAutoClaim: 1000,
+		DeadLetterStream: deadLetterStream,
+		Retry: &RetryPolicy{
+			Backoff:     Backoff{InitialInterval: 100 * time.Millisecond},
+			MaxAttempts: 3,
+		},
+	}
+
+	err := c.scheduleRetry(ctx, Message{XRangeEntry: valkey.XRangeEntry{ID: messageId}, Stream: streamName})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+}
+
+func TestInitConsumerRetryMaxInterval(t *testing.T) {
+	var S int64 = 1
+	c := &Consumer{
+		Client:             &client.ClientArgs{},
+		StreamName:         streamName,
+		GroupName:          groupName,
+		ConsumerName:       consumerName,
+		MinIdleAutoClaim:   1000,
+		BatchSizeAutoClaim: &S,
+		Retry: &RetryPolicy{
+			Backoff: Backoff{InitialInterval: 100 * time.Millisecond, MaxInterval: time.Minute},
+		},
+	}
+
+	err := c.InitConsumer(context.Background())
+	if !errors.Is(err, errors_custom.ErrInvalidConfig) {
+		t.Fatalf("expected %v, got %v", errors_custom.ErrInvalidConfig, err)
+	}
+
+	c.Retry.MaxInterval = time.Second
+	err = c.checkRetry()
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+}
+
+func TestInitConsumerRetryWithoutAutoClaim(t *testing.T) {
+	c := &Consumer{
+		Client:           &client.ClientArgs{},
+		StreamName:       streamName,
+		GroupName:        groupName,
+		ConsumerName:     consumerName,
+		MinIdleAutoClaim: 1000,
+		Retry: &RetryPolicy{
+			Backoff: Backoff{InitialInterval: 100 * time.Millisecond},
+		},
+	}
+
+	err := c.InitConsumer(context.Background())
+	if !errors.Is(err, errors_custom.ErrInvalidConfig) {
+		t.Fatalf("expected %v, got %v", errors_custom.ErrInvalidConfig, err)
+	}
+}
+
+func TestInitConsumerRetryWithoutMinIdleAutoClaim(t *testing.T) {
+	var S int64 = 1
+	c := &Consumer{
+		Client:             &client.ClientArgs{},
+		StreamName:         streamName,
+		GroupName:          groupName,
+		ConsumerName:       consumerName,
+		BatchSizeAutoClaim: &S,
+		Retry: &RetryPolicy{
+			Backoff: Backoff{InitialInterval: 100 * time.Millisecond},
+		},
+	}
+
+	err := c.InitConsumer(context.Background())
+	if !errors.Is(err, errors_custom.ErrInvalidConfig) {
+		t.Fatalf("expected %v, got %v", errors_custom.ErrInvalidConfig, err)
+	}
+}
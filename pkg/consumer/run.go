@@ -0,0 +1,312 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	errors_custom "github.com/enerBit/redsumer/v3/pkg/errors"
+	"github.com/enerBit/redsumer/v3/pkg/tracing"
+	"github.com/valkey-io/valkey-go"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// consumer_IDLE_WAIT is the pause of Run after a round that dispatched no message, when Block is zero.
+const consumer_IDLE_WAIT = 100 * time.Millisecond
+
+// Handler processes a single message delivered to the consumer.
+// Returning nil acknowledges the message. Returning an error leaves the message
+// pending, so it is delivered again by the pending or autoclaim phases.
+type Handler func(ctx context.Context, message valkey.XRangeEntry) error
+
+// StreamHandler is like Handler for a message tagged with the name of the stream it was read from.
+type StreamHandler func(ctx context.Context, message Message) error
+
+// Run is like RunStreams, but passes the entries to the handler without the stream they were read from.
+// It suits consumers of a single stream; when StreamNames is set, use RunStreams instead.
+func (c *Consumer) Run(ctx context.Context, handler Handler) error {
+	return c.RunStreams(ctx, func(ctx context.Context, message Message) error {
+		return handler(ctx, message.XRangeEntry)
+	})
+}
+
+// RunStreams consumes messages in a loop and passes each of them to the handler.
+// Messages are fetched with ConsumeStreams, so new, pending and claimed messages are
+// dispatched in the same order ConsumeStreams returns them.
+// Messages are processed by Workers goroutines, with at most MaxInFlight messages
+// dispatched and not yet acknowledged at any time. A message that is already being
+// processed is not dispatched again if a later ConsumeStreams call returns it.
+// Before calling the handler it checks the message is still owned by this consumer,
+// and after a successful handler call it acknowledges the message.
+// It returns nil when the context is cancelled or Shutdown is called, or the first error
+// returned by Valkey otherwise. In every case it waits for the messages already dispatched
+// before returning. Only one Run or RunStreams may be active on a Consumer at a time.
+func (c *Consumer) RunStreams(ctx context.Context, handler StreamHandler) error {
+	handlerCtx, cancelHandlers := context.WithCancel(ctx)
+	defer cancelHandlers()
+	fetchCtx, cancelFetch := context.WithCancel(handlerCtx)
+	defer cancelFetch()
+
+	state := &runState{
+		cancelFetch:    cancelFetch,
+		cancelHandlers: cancelHandlers,
+		done:           make(chan struct{}),
+	}
+	c.runMu.Lock()
+	if c.running != nil {
+		c.runMu.Unlock()
+		return errors_custom.ErrConsumerRunning
+	}
+	c.running = state
+	c.runMu.Unlock()
+
+	defer func() {
+		c.runMu.Lock()
+		c.running = nil
+		c.runMu.Unlock()
+		close(state.done)
+	}()
+
+	var once sync.Once
+	var runErr error
+	fail := func(err error) {
+		once.Do(func() {
+			runErr = err
+			cancelHandlers()
+		})
+	}
+
+	workers := max(c.Workers, 1)
+	maxInFlight := max(c.MaxInFlight, workers)
+	slots := make(chan struct{}, maxInFlight)
+	jobs := make(chan Message, maxInFlight)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for message := range jobs {
+				err := c.process(handlerCtx, handler, message)
+				c.untrack(message)
+				<-slots
+				if err != nil && handlerCtx.Err() == nil {
+					fail(err)
+				}
+			}
+		}()
+	}
+
+	c.dispatch(fetchCtx, jobs, slots, fail)
+	close(jobs)
+	wg.Wait()
+
+	return runErr
+}
+
+// runState holds what Shutdown needs to stop an active Run.
+type runState struct {
+	cancelFetch    context.CancelFunc
+	cancelHandlers context.CancelFunc
+	done           chan struct{}
+}
+
+// Shutdown stops the active Run from fetching new batches and waits for the messages
+// already dispatched to be handled and acknowledged.
+// If the context is done before, the context of the remaining handlers is cancelled,
+// their messages are left pending for other consumers, and the context error is returned
+// without waiting for those handlers to return.
+// If DeleteConsumerOnShutdown is set and every dispatched message was drained, the consumer
+// is then removed from the group with DeleteConsumer. If Run is not active, only the removal applies.
+// The Valkey client is not closed, since it may be shared with other consumers and producers
+// through the client cache.
+func (c *Consumer) Shutdown(ctx context.Context) error {
+	c.runMu.Lock()
+	state := c.running
+	c.runMu.Unlock()
+
+	if state == nil {
+		return c.deleteOnShutdown(ctx)
+	}
+
+	state.cancelFetch()
+	select {
+	case <-state.done:
+		return c.deleteOnShutdown(ctx)
+	case <-ctx.Done():
+		state.cancelHandlers()
+		return ctx.Err()
+	}
+}
+
+// deleteOnShutdown removes the consumer from the group if DeleteConsumerOnShutdown is set.
+func (c *Consumer) deleteOnShutdown(ctx context.Context) error {
+	if !c.DeleteConsumerOnShutdown {
+		return nil
+	}
+	return c.DeleteConsumer(ctx)
+}
+
+// dispatch fetches batches with ConsumeStreams and hands each message to the workers
+// until the context is cancelled or ConsumeStreams fails.
+// It also prunes the handler errors recorded for messages no longer owned by the consumer.
+// When Block is zero, it pauses for consumer_IDLE_WAIT after a round that dispatched no message,
+// so an idle consumer does not poll Valkey in a tight loop.
+func (c *Consumer) dispatch(ctx context.Context, jobs chan<- Message, slots chan struct{}, fail func(error)) {
+	pruned := time.Now()
+	for ctx.Err() == nil {
+		if time.Since(pruned) >= c.pruneErrorsInterval() {
+			err := c.pruneErrors(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					fail(err)
+				}
+				return
+			}
+			pruned = time.Now()
+		}
+
+		messages, err := c.ConsumeStreams(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				fail(err)
+			}
+			return
+		}
+
+		dispatched := 0
+		for _, message := range messages {
+			if !c.track(message) {
+				continue
+			}
+
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				c.untrack(message)
+				return
+			}
+			jobs <- message
+			dispatched++
+		}
+
+		if dispatched == 0 && c.Block <= 0 {
+			timer := time.NewTimer(consumer_IDLE_WAIT)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+	}
+}
+
+// messageKey identifies a message across all the streams of the consumer.
+func messageKey(message Message) string {
+	return message.Stream + "/" + message.ID
+}
+
+// track marks a message as in flight.
+// It returns false if the message is already in flight.
+func (c *Consumer) track(message Message) bool {
+	c.inFlightMu.Lock()
+	defer c.inFlightMu.Unlock()
+
+	if c.inFlight == nil {
+		c.inFlight = make(map[string]struct{})
+	}
+	if _, ok := c.inFlight[messageKey(message)]; ok {
+		return false
+	}
+	c.inFlight[messageKey(message)] = struct{}{}
+	return true
+}
+
+// untrack removes a message from the in-flight set.
+func (c *Consumer) untrack(message Message) {
+	c.inFlightMu.Lock()
+	defer c.inFlightMu.Unlock()
+
+	delete(c.inFlight, messageKey(message))
+}
+
+// isInFlight reports whether a message is currently being processed.
+func (c *Consumer) isInFlight(message Message) bool {
+	c.inFlightMu.Lock()
+	defer c.inFlightMu.Unlock()
+
+	_, ok := c.inFlight[messageKey(message)]
+	return ok
+}
+
+// hasInFlight reports whether any message is currently being processed.
+func (c *Consumer) hasInFlight() bool {
+	c.inFlightMu.Lock()
+	defer c.inFlightMu.Unlock()
+
+	return len(c.inFlight) != 0
+}
+
+// process runs the handler for a single message and acknowledges it on success.
+// A message that is no longer owned by the consumer is skipped and its recorded error
+// forgotten, and a message whose handler fails is left pending, with its error kept for
+// dead-lettering, and scheduled for redelivery if Retry is set.
+// The handler runs in a span linked to the span that produced the message.
+func (c *Consumer) process(ctx context.Context, handler StreamHandler, message Message) (err error) {
+	ctx, span := c.StartProcessSpan(ctx, message)
+	defer func() { tracing.End(span, err) }()
+
+	isMine, err := c.StillMineInStream(ctx, message.Stream, message.ID)
+	if err != nil {
+		return err
+	}
+	if !isMine {
+		c.forgetError(message)
+		return nil
+	}
+
+	start := time.Now()
+	err = handler(ctx, message)
+	if c.Metrics != nil {
+		c.Metrics.Handled(message.Stream, c.GroupName, time.Since(start), err)
+	}
+	if err != nil {
+		c.recordError(message, err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return c.scheduleRetry(ctx, message)
+	}
+
+	err = c.AcknowledgeMessageInStream(ctx, message.Stream, message.ID)
+	if err != nil && !errors.Is(err, errors_custom.ErrNoAckedMessage) {
+		return err
+	}
+
+	c.forgetError(message)
+	return nil
+}
+
+// StartProcessSpan starts the span of the processing of a message, linked to the span that produced it
+// when the message carries a trace context. Run calls it around each handler call; callers processing
+// the messages returned by ConsumeStreams can use it to the same effect, ending the span once done.
+func (c *Consumer) StartProcessSpan(ctx context.Context, message Message) (context.Context, trace.Span) {
+	opts := []trace.SpanStartOption{
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			tracing.System,
+			tracing.AttributeOperation.String("process"),
+			tracing.AttributeDestination.String(message.Stream),
+			tracing.AttributeGroup.String(c.GroupName),
+			tracing.AttributeMessageID.String(message.ID),
+		),
+	}
+
+	remote := trace.SpanContextFromContext(tracing.Extract(context.Background(), c.Propagator, message.FieldValues))
+	if remote.IsValid() {
+		opts = append(opts, trace.WithLinks(trace.Link{SpanContext: remote}))
+	}
+	return tracing.Start(ctx, c.TracerProvider, "process", opts...)
+}
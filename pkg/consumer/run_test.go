@@ -0,0 +1,546 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/enerBit/redsumer/v3/pkg/client"
+	"github.com/valkey-io/valkey-go"
+	"github.com/valkey-io/valkey-go/mock"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/mock/gomock"
+)
+
+// xreadResult builds an XREADGROUP reply with one entry per message ID.
+func xreadResult(stream string, messageIds ...string) valkey.ValkeyResult {
+	entries := make([]valkey.ValkeyMessage, 0, len(messageIds))
+	for _, id := range messageIds {
+		entries = append(entries, mock.ValkeyArray(mock.ValkeyString(id), mock.ValkeyArray(mock.ValkeyString("key"), mock.ValkeyString("value"))))
+	}
+	return mock.Result(mock.ValkeyMap(map[string]valkey.ValkeyMessage{stream: mock.ValkeyArray(entries...)}))
+}
+
+func TestRunSuccess(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	db := mock.NewClient(ctrl)
+
+	messageId := "1676389477-0"
+
+	gomock.InOrder(
+		db.EXPECT().Do(gomock.Any(), mock.Match("XREADGROUP", "GROUP", groupName, consumerName, "COUNT", "1", "STREAMS", streamName, consumer_NEVER_DELIVERED_TO_OTHER_CONSUMERS_SO_FAR)).Return(xreadResult(streamName, messageId)),
+		db.EXPECT().Do(gomock.Any(), mock.Match("XPENDING", streamName, groupName, "IDLE", "0", messageId, messageId, "1", consumerName)).Return(mock.Result(mock.ValkeyArray(valkey.ValkeyMessage{}))),
+		db.EXPECT().Do(gomock.Any(), mock.Match("XACK", streamName, groupName, messageId)).DoAndReturn(func(ctx context.Context, cmd valkey.Completed) valkey.ValkeyResult {
+			cancel()
+			return mock.Result(mock.ValkeyInt64(1))
+		}),
+	)
+	db.EXPECT().Do(gomock.Any(), mock.Match("XREADGROUP", "GROUP", groupName, consumerName, "COUNT", "1", "STREAMS", streamName, consumer_NEVER_DELIVERED_TO_OTHER_CONSUMERS_SO_FAR)).Return(mock.Result(mock.ValkeyNil())).AnyTimes()
+
+	clientArg := &client.ClientArgs{
+		Instance: db,
+	}
+	c := &Consumer{
+		Client:              clientArg,
+		StreamName:          streamName,
+		GroupName:           groupName,
+		ConsumerName:        consumerName,
+		BatchSizeNewMessage: 1,
+	}
+
+	var handled []string
+	err := c.Run(ctx, func(ctx context.Context, message valkey.XRangeEntry) error {
+		handled = append(handled, message.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	if len(handled) != 1 || handled[0] != messageId {
+		t.Fatalf("expected message %s to be handled, got %v", messageId, handled)
+	}
+}
+
+func TestRunStreams(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	db := mock.NewClient(ctrl)
+
+	messageId := "1676389477-0"
+
+	gomock.InOrder(
+		db.EXPECT().Do(gomock.Any(), mock.Match("XREADGROUP", "GROUP", groupName, consumerName, "COUNT", "1", "STREAMS", streamName, consumer_NEVER_DELIVERED_TO_OTHER_CONSUMERS_SO_FAR)).Return(xreadResult(streamName, messageId)),
+		db.EXPECT().Do(gomock.Any(), mock.Match("XPENDING", streamName, groupName, "IDLE", "0", messageId, messageId, "1", consumerName)).Return(mock.Result(mock.ValkeyArray(valkey.ValkeyMessage{}))),
+		db.EXPECT().Do(gomock.Any(), mock.Match("XACK", streamName, groupName, messageId)).DoAndReturn(func(ctx context.Context, cmd valkey.Completed) valkey.ValkeyResult {
+			cancel()
+			return mock.Result(mock.ValkeyInt64(1))
+		}),
+	)
+	db.EXPECT().Do(gomock.Any(), mock.Match("XREADGROUP", "GROUP", groupName, consumerName, "COUNT", "1", "STREAMS", streamName, consumer_NEVER_DELIVERED_TO_OTHER_CONSUMERS_SO_FAR)).Return(mock.Result(mock.ValkeyNil())).AnyTimes()
+
+	clientArg := &client.ClientArgs{
+		Instance: db,
+	}
+	c := &Consumer{
+		Client:              clientArg,
+		StreamName:          streamName,
+		GroupName:           groupName,
+		ConsumerName:        consumerName,
+		BatchSizeNewMessage: 1,
+	}
+
+	var handled []Message
+	err := c.RunStreams(ctx, func(ctx context.Context, message Message) error {
+		handled = append(handled, message)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	if len(handled) != 1 || handled[0].ID != messageId || handled[0].Stream != streamName {
+		t.Fatalf("expected message %s from %s to be handled, got %v", messageId, streamName, handled)
+	}
+}
+
+func TestRunHandlerError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	db := mock.NewClient(ctrl)
+
+	messageId := "1676389477-0"
+
+	gomock.InOrder(
+		db.EXPECT().Do(gomock.Any(), mock.Match("XREADGROUP", "GROUP", groupName, consumerName, "COUNT", "1", "STREAMS", streamName, consumer_NEVER_DELIVERED_TO_OTHER_CONSUMERS_SO_FAR)).Return(xreadResult(streamName, messageId)),
+		db.EXPECT().Do(gomock.Any(), mock.Match("XPENDING", streamName, groupName, "IDLE", "0", messageId, messageId, "1", consumerName)).Return(mock.Result(mock.ValkeyArray(valkey.ValkeyMessage{}))),
+	)
+	db.EXPECT().Do(gomock.Any(), mock.Match("XREADGROUP", "GROUP", groupName, consumerName, "COUNT", "1", "STREAMS", streamName, consumer_NEVER_DELIVERED_TO_OTHER_CONSUMERS_SO_FAR)).Return(mock.Result(mock.ValkeyNil())).AnyTimes()
+
+	clientArg := &client.ClientArgs{
+		Instance: db,
+	}
+	c := &Consumer{
+		Client:              clientArg,
+		StreamName:          streamName,
+		GroupName:           groupName,
+		ConsumerName:        consumerName,
+		BatchSizeNewMessage: 1,
+	}
+
+	err := c.Run(ctx, func(ctx context.Context, message valkey.XRangeEntry) error {
+		cancel()
+		return errors.New("error")
+	})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+}
+
+func TestRunConsumeError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	db := mock.NewClient(ctrl)
+
+	db.EXPECT().Do(gomock.Any(), mock.Match("XREADGROUP", "GROUP", groupName, consumerName, "COUNT", "1", "STREAMS", streamName, consumer_NEVER_DELIVERED_TO_OTHER_CONSUMERS_SO_FAR)).Return(mock.Result(mock.ValkeyError("error")))
+
+	clientArg := &client.ClientArgs{
+		Instance: db,
+	}
+	c := &Consumer{
+		Client:              clientArg,
+		StreamName:          streamName,
+		GroupName:           groupName,
+		ConsumerName:        consumerName,
+		BatchSizeNewMessage: 1,
+	}
+
+	err := c.Run(ctx, func(ctx context.Context, message valkey.XRangeEntry) error {
+		t.Fatalf("handler must not be called")
+		return nil
+	})
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+}
+
+func TestRunWorkers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	db := mock.NewClient(ctrl)
+
+	messageIds := []string{"1676389477-0", "1676389477-1"}
+
+	db.EXPECT().Do(gomock.Any(), mock.Match("XREADGROUP", "GROUP", groupName, consumerName, "COUNT", "2", "STREAMS", streamName, consumer_NEVER_DELIVERED_TO_OTHER_CONSUMERS_SO_FAR)).Return(xreadResult(streamName, messageIds...))
+	db.EXPECT().Do(gomock.Any(), mock.Match("XREADGROUP", "GROUP", groupName, consumerName, "COUNT", "2", "STREAMS", streamName, consumer_NEVER_DELIVERED_TO_OTHER_CONSUMERS_SO_FAR)).Return(mock.Result(mock.ValkeyNil())).AnyTimes()
+
+	var acked atomic.Int32
+	for _, messageId := range messageIds {
+		db.EXPECT().Do(gomock.Any(), mock.Match("XPENDING", streamName, groupName, "IDLE", "0", messageId, messageId, "1", consumerName)).Return(mock.Result(mock.ValkeyArray(valkey.ValkeyMessage{})))
+		db.EXPECT().Do(gomock.Any(), mock.Match("XACK", streamName, groupName, messageId)).DoAndReturn(func(ctx context.Context, cmd valkey.Completed) valkey.ValkeyResult {
+			if acked.Add(1) == int32(len(messageIds)) {
+				cancel()
+			}
+			return mock.Result(mock.ValkeyInt64(1))
+		})
+	}
+
+	clientArg := &client.ClientArgs{
+		Instance: db,
+	}
+	c := &Consumer{
+		Client:              clientArg,
+		StreamName:          streamName,
+		GroupName:           groupName,
+		ConsumerName:        consumerName,
+		BatchSizeNewMessage: 2,
+		Workers:             2,
+	}
+
+	// Both handlers must run at the same time to get past the barrier.
+	started := make(chan struct{}, len(messageIds))
+	err := c.Run(ctx, func(ctx context.Context, message valkey.XRangeEntry) error {
+		started <- struct{}{}
+		deadline := time.After(time.Second)
+		for len(started) < len(messageIds) {
+			select {
+			case <-deadline:
+				return errors.New("handlers did not run concurrently")
+			case <-time.After(time.Millisecond):
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	if acked.Load() != int32(len(messageIds)) {
+		t.Fatalf("expected %d acked messages, got %d", len(messageIds), acked.Load())
+	}
+}
+
+func TestRunPendingWhileInFlight(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	db := mock.NewClient(ctrl)
+
+	messageId := "1676389477-0"
+	var handled atomic.Bool
+
+	db.EXPECT().Do(gomock.Any(), mock.Match("XREADGROUP", "GROUP", groupName, consumerName, "COUNT", "1", "STREAMS", streamName, consumer_NEVER_DELIVERED_TO_OTHER_CONSUMERS_SO_FAR)).Return(xreadResult(streamName, messageId))
+	db.EXPECT().Do(gomock.Any(), mock.Match("XREADGROUP", "GROUP", groupName, consumerName, "COUNT", "1", "STREAMS", streamName, consumer_NEVER_DELIVERED_TO_OTHER_CONSUMERS_SO_FAR)).Return(mock.Result(mock.ValkeyNil())).AnyTimes()
+	db.EXPECT().Do(gomock.Any(), mock.Match("XREADGROUP", "GROUP", groupName, consumerName, "COUNT", "1", "STREAMS", streamName, consumer_INITIAL_STREAM_ID)).DoAndReturn(func(ctx context.Context, cmd valkey.Completed) valkey.ValkeyResult {
+		if !handled.Load() {
+			t.Errorf("expected no pending read while the handler is running")
+		}
+		return mock.Result(mock.ValkeyNil())
+	}).AnyTimes()
+	db.EXPECT().Do(gomock.Any(), mock.Match("XPENDING", streamName, groupName, "IDLE", "0", messageId, messageId, "1", consumerName)).Return(mock.Result(mock.ValkeyArray(valkey.ValkeyMessage{})))
+	db.EXPECT().Do(gomock.Any(), mock.Match("XACK", streamName, groupName, messageId)).DoAndReturn(func(ctx context.Context, cmd valkey.Completed) valkey.ValkeyResult {
+		cancel()
+		return mock.Result(mock.ValkeyInt64(1))
+	})
+
+	clientArg := &client.ClientArgs{
+		Instance: db,
+	}
+	var S int64 = 1
+	c := &Consumer{
+		Client:              clientArg,
+		StreamName:          streamName,
+		GroupName:           groupName,
+		ConsumerName:        consumerName,
+		BatchSizeNewMessage: 1,
+		BatchSizePending:    &S,
+		Workers:             2,
+	}
+
+	err := c.Run(ctx, func(ctx context.Context, message valkey.XRangeEntry) error {
+		// Keep the message in flight while the dispatcher polls again
+		time.Sleep(50 * time.Millisecond)
+		handled.Store(true)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+}
+
+func TestRunIdleWait(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*consumer_IDLE_WAIT)
+	defer cancel()
+	db := mock.NewClient(ctrl)
+
+	var reads atomic.Int32
+	db.EXPECT().Do(gomock.Any(), mock.Match("XREADGROUP", "GROUP", groupName, consumerName, "COUNT", "1", "STREAMS", streamName, consumer_NEVER_DELIVERED_TO_OTHER_CONSUMERS_SO_FAR)).DoAndReturn(func(ctx context.Context, cmd valkey.Completed) valkey.ValkeyResult {
+		reads.Add(1)
+		return mock.Result(mock.ValkeyNil())
+	}).AnyTimes()
+
+	clientArg := &client.ClientArgs{
+		Instance: db,
+	}
+	c := &Consumer{
+		Client:              clientArg,
+		StreamName:          streamName,
+		GroupName:           groupName,
+		ConsumerName:        consumerName,
+		BatchSizeNewMessage: 1,
+	}
+
+	err := c.Run(ctx, func(ctx context.Context, message valkey.XRangeEntry) error {
+		t.Fatalf("handler must not be called")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	// One read per idle wait, instead of back-to-back reads
+	if n := reads.Load(); n < 1 || n > 4 {
+		t.Fatalf("expected between 1 and 4 reads, got %d", n)
+	}
+}
+
+func TestTrackInFlight(t *testing.T) {
+	c := &Consumer{}
+
+	message := Message{XRangeEntry: valkey.XRangeEntry{ID: "1676389477-0"}, Stream: streamName}
+	if !c.track(message) {
+		t.Fatalf("expected message to be tracked")
+	}
+	if c.track(message) {
+		t.Fatalf("expected message already in flight")
+	}
+
+	other := Message{XRangeEntry: message.XRangeEntry, Stream: "other-" + streamName}
+	if !c.track(other) {
+		t.Fatalf("expected message with the same ID in another stream to be tracked")
+	}
+
+	c.untrack(message)
+	if !c.track(message) {
+		t.Fatalf("expected message to be tracked again")
+	}
+}
+
+func TestShutdownDrainsInFlight(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	db := mock.NewClient(ctrl)
+
+	messageId := "1676389477-0"
+
+	// fetchCtx is the context of the fetches, cancelled by Shutdown
+	fetchCtx := make(chan context.Context, 1)
+	db.EXPECT().Do(gomock.Any(), mock.Match("XREADGROUP", "GROUP", groupName, consumerName, "COUNT", "1", "STREAMS", streamName, consumer_NEVER_DELIVERED_TO_OTHER_CONSUMERS_SO_FAR)).DoAndReturn(func(ctx context.Context, cmd valkey.Completed) valkey.ValkeyResult {
+		fetchCtx <- ctx
+		return xreadResult(streamName, messageId)
+	})
+	db.EXPECT().Do(gomock.Any(), mock.Match("XREADGROUP", "GROUP", groupName, consumerName, "COUNT", "1", "STREAMS", streamName, consumer_NEVER_DELIVERED_TO_OTHER_CONSUMERS_SO_FAR)).Return(mock.Result(mock.ValkeyNil())).AnyTimes()
+	db.EXPECT().Do(gomock.Any(), mock.Match("XPENDING", streamName, groupName, "IDLE", "0", messageId, messageId, "1", consumerName)).Return(mock.Result(mock.ValkeyArray(valkey.ValkeyMessage{})))
+	db.EXPECT().Do(gomock.Any(), mock.Match("XACK", streamName, groupName, messageId)).Return(mock.Result(mock.ValkeyInt64(1)))
+
+	clientArg := &client.ClientArgs{
+		Instance: db,
+	}
+	c := &Consumer{
+		Client:              clientArg,
+		StreamName:          streamName,
+		GroupName:           groupName,
+		ConsumerName:        consumerName,
+		BatchSizeNewMessage: 1,
+	}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- c.Run(ctx, func(ctx context.Context, message valkey.XRangeEntry) error {
+			close(started)
+			<-release
+			return ctx.Err()
+		})
+	}()
+
+	<-started
+	shutdown := make(chan error)
+	go func() {
+		shutdown <- c.Shutdown(ctx)
+	}()
+
+	// The handler is only released once Shutdown stopped the fetches and is waiting for it
+	select {
+	case <-(<-fetchCtx).Done():
+	case <-time.After(time.Second):
+		t.Fatalf("expected Shutdown to stop fetching")
+	}
+	select {
+	case err := <-shutdown:
+		t.Fatalf("expected Shutdown to wait for the handler, returned %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+
+	err := <-shutdown
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	err = <-done
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	db := mock.NewClient(ctrl)
+
+	messageId := "1676389477-0"
+
+	db.EXPECT().Do(gomock.Any(), mock.Match("XREADGROUP", "GROUP", groupName, consumerName, "COUNT", "1", "STREAMS", streamName, consumer_NEVER_DELIVERED_TO_OTHER_CONSUMERS_SO_FAR)).Return(xreadResult(streamName, messageId))
+	db.EXPECT().Do(gomock.Any(), mock.Match("XREADGROUP", "GROUP", groupName, consumerName, "COUNT", "1", "STREAMS", streamName, consumer_NEVER_DELIVERED_TO_OTHER_CONSUMERS_SO_FAR)).Return(mock.Result(mock.ValkeyNil())).AnyTimes()
+	db.EXPECT().Do(gomock.Any(), mock.Match("XPENDING", streamName, groupName, "IDLE", "0", messageId, messageId, "1", consumerName)).Return(mock.Result(mock.ValkeyArray(valkey.ValkeyMessage{})))
+
+	clientArg := &client.ClientArgs{
+		Instance: db,
+	}
+	c := &Consumer{
+		Client:              clientArg,
+		StreamName:          streamName,
+		GroupName:           groupName,
+		ConsumerName:        consumerName,
+		BatchSizeNewMessage: 1,
+	}
+
+	started := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- c.Run(ctx, func(ctx context.Context, message valkey.XRangeEntry) error {
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		})
+	}()
+
+	<-started
+	shutdownCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+
+	err := c.Shutdown(shutdownCtx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context deadline exceeded, got %v", err)
+	}
+
+	err = <-done
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+}
+
+func TestShutdownNotRunning(t *testing.T) {
+	c := &Consumer{}
+
+	err := c.Shutdown(context.Background())
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+}
+
+func TestRunTracing(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	db := mock.NewClient(ctrl)
+
+	messageId := "1676389477-0"
+	traceparent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	entry := mock.ValkeyArray(mock.ValkeyString(messageId), mock.ValkeyArray(mock.ValkeyString("key"), mock.ValkeyString("value"), mock.ValkeyString("redsumer-traceparent"), mock.ValkeyString(traceparent)))
+
+	gomock.InOrder(
+		db.EXPECT().Do(gomock.Any(), mock.Match("XREADGROUP", "GROUP", groupName, consumerName, "COUNT", "1", "STREAMS", streamName, consumer_NEVER_DELIVERED_TO_OTHER_CONSUMERS_SO_FAR)).Return(mock.Result(mock.ValkeyMap(map[string]valkey.ValkeyMessage{streamName: mock.ValkeyArray(entry)}))),
+		db.EXPECT().Do(gomock.Any(), mock.Match("XPENDING", streamName, groupName, "IDLE", "0", messageId, messageId, "1", consumerName)).Return(mock.Result(mock.ValkeyArray(valkey.ValkeyMessage{}))),
+		db.EXPECT().Do(gomock.Any(), mock.Match("XACK", streamName, groupName, messageId)).DoAndReturn(func(ctx context.Context, cmd valkey.Completed) valkey.ValkeyResult {
+			cancel()
+			return mock.Result(mock.ValkeyInt64(1))
+		}),
+	)
+	db.EXPECT().Do(gomock.Any(), mock.Match("XREADGROUP", "GROUP", groupName, consumerName, "COUNT", "1", "STREAMS", streamName, consumer_NEVER_DELIVERED_TO_OTHER_CONSUMERS_SO_FAR)).Return(mock.Result(mock.ValkeyNil())).AnyTimes()
+
+	recorder := tracetest.NewSpanRecorder()
+	c := &Consumer{
+		Client:              &client.ClientArgs{Instance: db},
+		StreamName:          streamName,
+		GroupName:           groupName,
+		ConsumerName:        consumerName,
+		BatchSizeNewMessage: 1,
+		TracerProvider:      sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)),
+		Propagator:          propagation.TraceContext{},
+	}
+
+	var handlerSpan trace.SpanContext
+	err := c.Run(ctx, func(ctx context.Context, message valkey.XRangeEntry) error {
+		handlerSpan = trace.SpanContextFromContext(ctx)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	var process, ack sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		switch span.Name() {
+		case "process":
+			process = span
+		case "XACK":
+			ack = span
+		}
+	}
+	if process == nil || ack == nil {
+		t.Fatalf("expected process and XACK spans, got %v", recorder.Ended())
+	}
+	if process.SpanContext().SpanID() != handlerSpan.SpanID() {
+		t.Fatalf("expected the handler to run in the process span")
+	}
+	if len(process.Links()) != 1 || process.Links()[0].SpanContext.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("expected a link to the producing span, got %v", process.Links())
+	}
+	if ack.Parent().SpanID() != process.SpanContext().SpanID() {
+		t.Fatalf("expected the XACK span to be a child of the process span")
+	}
+}
@@ -0,0 +1,144 @@
+package envelope
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	errors_custom "github.com/enerBit/redsumer/v3/pkg/errors"
+	"github.com/enerBit/redsumer/v3/pkg/producer"
+	"github.com/valkey-io/valkey-go"
+)
+
+// FieldPrefix starts the names of the entry fields reserved for redsumer metadata.
+// Message fields cannot start with it.
+const FieldPrefix = "redsumer-"
+
+// Reserved entry fields holding the headers.
+const (
+	FieldMessageType   = FieldPrefix + "message-type"
+	FieldSchemaVersion = FieldPrefix + "schema-version"
+	FieldProducer      = FieldPrefix + "producer"
+	FieldCreatedAt     = FieldPrefix + "created-at"
+	FieldCorrelationID = FieldPrefix + "correlation-id"
+	FieldCausationID   = FieldPrefix + "causation-id"
+)
+
+// Headers is the standard metadata of a message. Empty headers are not written.
+type Headers struct {
+	// MessageType names the kind of message, such as "order.created".
+	MessageType string
+	// SchemaVersion is the version of the schema of the message fields.
+	SchemaVersion string
+	// Producer names the service that produced the message.
+	Producer string
+	// CreatedAt is the time the message was created, written in RFC 3339 format with nanoseconds.
+	CreatedAt time.Time
+	// CorrelationID is shared by every message of the same conversation or workflow.
+	CorrelationID string
+	// CausationID is the ID of the message that caused this one.
+	CausationID string
+}
+
+// CausedBy returns the headers of a message caused by another one: it keeps the correlation ID
+// of the cause, or uses the ID of the cause when it has none, and uses the ID of the cause as causation ID.
+func (h Headers) CausedBy(cause Headers, causeID string) Headers {
+	h.CorrelationID = cause.CorrelationID
+	if h.CorrelationID == "" {
+		h.CorrelationID = causeID
+	}
+	h.CausationID = causeID
+	return h
+}
+
+// Wrap returns the fields of an entry holding the message fields and the headers.
+// It fails with ErrReservedField when a message field starts with FieldPrefix.
+func Wrap(message map[string]string, headers Headers) (map[string]string, error) {
+	fields := make(map[string]string, len(message)+6)
+	for k, v := range message {
+		if strings.HasPrefix(k, FieldPrefix) {
+			return nil, fmt.Errorf("%w: %s", errors_custom.ErrReservedField, k)
+		}
+		fields[k] = v
+	}
+
+	set := func(field string, value string) {
+		if value != "" {
+			fields[field] = value
+		}
+	}
+	set(FieldMessageType, headers.MessageType)
+	set(FieldSchemaVersion, headers.SchemaVersion)
+	set(FieldProducer, headers.Producer)
+	if !headers.CreatedAt.IsZero() {
+		fields[FieldCreatedAt] = headers.CreatedAt.UTC().Format(time.RFC3339Nano)
+	}
+	set(FieldCorrelationID, headers.CorrelationID)
+	set(FieldCausationID, headers.CausationID)
+
+	return fields, nil
+}
+
+// Unwrap splits the fields of an entry into its headers and its message fields.
+// Fields starting with FieldPrefix are never returned as message fields.
+// It fails with ErrDecode when the created-at header is not a valid timestamp.
+func Unwrap(fields map[string]string) (Headers, map[string]string, error) {
+	headers := Headers{
+		MessageType:   fields[FieldMessageType],
+		SchemaVersion: fields[FieldSchemaVersion],
+		Producer:      fields[FieldProducer],
+		CorrelationID: fields[FieldCorrelationID],
+		CausationID:   fields[FieldCausationID],
+	}
+	if createdAt, ok := fields[FieldCreatedAt]; ok {
+		t, err := time.Parse(time.RFC3339Nano, createdAt)
+		if err != nil {
+			return Headers{}, nil, fmt.Errorf("%w: %s: %w", errors_custom.ErrDecode, FieldCreatedAt, err)
+		}
+		headers.CreatedAt = t
+	}
+
+	message := make(map[string]string, len(fields))
+	for k, v := range fields {
+		if !strings.HasPrefix(k, FieldPrefix) {
+			message[k] = v
+		}
+	}
+	return headers, message, nil
+}
+
+// FromEntry returns the headers and the message fields of a consumed entry, as Unwrap.
+func FromEntry(entry valkey.XRangeEntry) (Headers, map[string]string, error) {
+	return Unwrap(entry.FieldValues)
+}
+
+// Producer sends messages with headers through a producer.Producer.
+type Producer struct {
+	Producer *producer.Producer
+
+	// Name is the Producer header of the messages that do not set it.
+	Name string
+}
+
+// Produce sends a message with its headers to the specified stream, and returns the ID of the new entry.
+// The Producer header defaults to Name and CreatedAt to the current time.
+func (p *Producer) Produce(ctx context.Context, message map[string]string, streamName string, headers Headers) (string, error) {
+	return p.ProduceWithOptions(ctx, message, streamName, headers, producer.ProduceOptions{})
+}
+
+// ProduceWithOptions sends a message with its headers to the specified stream with per-message settings.
+func (p *Producer) ProduceWithOptions(ctx context.Context, message map[string]string, streamName string, headers Headers, opts producer.ProduceOptions) (string, error) {
+	if headers.Producer == "" {
+		headers.Producer = p.Name
+	}
+	if headers.CreatedAt.IsZero() {
+		headers.CreatedAt = time.Now()
+	}
+
+	fields, err := Wrap(message, headers)
+	if err != nil {
+		return "", err
+	}
+	return p.Producer.ProduceWithOptions(ctx, fields, streamName, opts)
+}
@@ -0,0 +1,122 @@
+package envelope
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/enerBit/redsumer/v3/pkg/client"
+	errors_custom "github.com/enerBit/redsumer/v3/pkg/errors"
+	"github.com/enerBit/redsumer/v3/pkg/producer"
+	"github.com/valkey-io/valkey-go"
+	"github.com/valkey-io/valkey-go/mock"
+	"go.uber.org/mock/gomock"
+)
+
+const streamName string = "stream-test"
+
+func TestWrapUnwrap(t *testing.T) {
+	headers := Headers{
+		MessageType:   "order.created",
+		SchemaVersion: "2",
+		Producer:      "orders",
+		CreatedAt:     time.Date(2023, 2, 14, 15, 4, 37, 123456789, time.UTC),
+		CorrelationID: "correlation",
+		CausationID:   "1676389477000-0",
+	}
+
+	fields, err := Wrap(map[string]string{"id": "1"}, headers)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if fields[FieldCreatedAt] != "2023-02-14T15:04:37.123456789Z" || fields[FieldMessageType] != "order.created" || fields["id"] != "1" {
+		t.Fatalf("unexpected fields %v", fields)
+	}
+
+	fields["redsumer-original-stream"] = "orders"
+	decoded, message, err := FromEntry(valkey.XRangeEntry{ID: "1676389477000-1", FieldValues: fields})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if decoded != headers {
+		t.Fatalf("expected %+v, got %+v", headers, decoded)
+	}
+	if !reflect.DeepEqual(message, map[string]string{"id": "1"}) {
+		t.Fatalf("unexpected message %v", message)
+	}
+}
+
+func TestWrapUnwrapErrors(t *testing.T) {
+	_, err := Wrap(map[string]string{FieldProducer: "other"}, Headers{})
+	if !errors.Is(err, errors_custom.ErrReservedField) {
+		t.Fatalf("expected %v, got %v", errors_custom.ErrReservedField, err)
+	}
+
+	_, _, err = Unwrap(map[string]string{FieldCreatedAt: "yesterday"})
+	if !errors.Is(err, errors_custom.ErrDecode) {
+		t.Fatalf("expected %v, got %v", errors_custom.ErrDecode, err)
+	}
+
+	headers, message, err := Unwrap(map[string]string{"id": "1"})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if headers != (Headers{}) || message["id"] != "1" {
+		t.Fatalf("unexpected headers %+v or message %v", headers, message)
+	}
+}
+
+func TestCausedBy(t *testing.T) {
+	headers := Headers{MessageType: "invoice.created"}.CausedBy(Headers{}, "1676389477000-0")
+	if headers.CorrelationID != "1676389477000-0" || headers.CausationID != "1676389477000-0" {
+		t.Fatalf("unexpected headers %+v", headers)
+	}
+
+	headers = headers.CausedBy(Headers{CorrelationID: "correlation"}, "1676389477000-1")
+	if headers.CorrelationID != "correlation" || headers.CausationID != "1676389477000-1" || headers.MessageType != "invoice.created" {
+		t.Fatalf("unexpected headers %+v", headers)
+	}
+}
+
+func TestProducerProduce(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	db := mock.NewClient(ctrl)
+
+	var fields map[string]string
+	db.EXPECT().Do(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, cmd valkey.Completed) valkey.ValkeyResult {
+		args := cmd.Commands()
+		fields = make(map[string]string)
+		for i := 3; i+1 < len(args); i += 2 {
+			fields[args[i]] = args[i+1]
+		}
+		return mock.Result(mock.ValkeyString("1676389477000-0"))
+	})
+
+	p := Producer{
+		Producer: &producer.Producer{Client: &client.ClientArgs{Instance: db}},
+		Name:     "orders",
+	}
+	id, err := p.Produce(ctx, map[string]string{"id": "1"}, streamName, Headers{MessageType: "order.created"})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if id != "1676389477000-0" {
+		t.Fatalf("expected 1676389477000-0, got %s", id)
+	}
+
+	headers, message, err := Unwrap(fields)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if headers.Producer != "orders" || headers.MessageType != "order.created" || time.Since(headers.CreatedAt) > time.Minute {
+		t.Fatalf("unexpected headers %+v", headers)
+	}
+	if message["id"] != "1" {
+		t.Fatalf("unexpected message %v", message)
+	}
+}
@@ -7,4 +7,13 @@ var (
 	ErrKeyNotFound  = errors.New("key not found")
 	ErrGroupNotCreated = errors.New("group not created")
 	ErrNoAckedMessage = errors.New("no acked message")
+	ErrConsumerRunning = errors.New("consumer already running")
+	ErrCrossSlot = errors.New("keys hash to different cluster slots")
+	ErrInvalidConfig = errors.New("invalid configuration")
+	ErrGroupNotFound = errors.New("group not found")
+	ErrNoRecentRead = errors.New("no recent successful read")
+	ErrProducerClosed = errors.New("producer closed")
+	ErrDecode = errors.New("cannot decode message")
+	ErrContentType = errors.New("unexpected content type")
+	ErrReservedField = errors.New("reserved field name")
 )
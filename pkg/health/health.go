@@ -0,0 +1,76 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// health_DEFAULT_TIMEOUT bounds the checks of a probe when Handler.Timeout is zero.
+const health_DEFAULT_TIMEOUT = 5 * time.Second
+
+// Checker reports whether a component is healthy. consumer.Consumer and producer.Producer implement it.
+type Checker interface {
+	Health(ctx context.Context) error
+}
+
+// CheckerFunc adapts a function to the Checker interface.
+type CheckerFunc func(ctx context.Context) error
+
+// Health calls f.
+func (f CheckerFunc) Health(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Handler serves Kubernetes probes: /healthz runs the Live checkers and /readyz the Ready checkers.
+// A probe answers 200 when all its checkers pass, and 503 with the failed checks otherwise.
+// A probe without checkers always passes.
+type Handler struct {
+	// Live are the checkers of the liveness probe. Only include checks whose failure is fixed
+	// by restarting the process, such as a consumer that stopped reading.
+	Live []Checker
+	// Ready are the checkers of the readiness probe.
+	Ready []Checker
+	// Timeout bounds the checks of a probe. Zero means 5 seconds.
+	Timeout time.Duration
+}
+
+// ServeHTTP serves the /healthz and /readyz probes.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var checkers []Checker
+	switch r.URL.Path {
+	case "/healthz":
+		checkers = h.Live
+	case "/readyz":
+		checkers = h.Ready
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = health_DEFAULT_TIMEOUT
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	err := check(ctx, checkers)
+	if err != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(err.Error() + "\n"))
+		return
+	}
+	w.Write([]byte("ok\n"))
+}
+
+// check runs every checker and joins their errors.
+func check(ctx context.Context, checkers []Checker) error {
+	var errs []error
+	for _, checker := range checkers {
+		errs = append(errs, checker.Health(ctx))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,58 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	failing := CheckerFunc(func(ctx context.Context) error {
+		return errors.New("stream key not found")
+	})
+	passing := CheckerFunc(func(ctx context.Context) error {
+		return nil
+	})
+	h := &Handler{
+		Live:  []Checker{passing},
+		Ready: []Checker{passing, failing},
+	}
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/healthz", http.StatusOK, "ok"},
+		{"/readyz", http.StatusServiceUnavailable, "stream key not found"},
+		{"/other", http.StatusNotFound, ""},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.path, nil))
+
+		if w.Code != test.status {
+			t.Errorf("expected status %d for %s, got %d", test.status, test.path, w.Code)
+		}
+		if !strings.Contains(w.Body.String(), test.body) {
+			t.Errorf("expected body %q for %s, got %q", test.body, test.path, w.Body.String())
+		}
+	}
+}
+
+func TestHandlerTimeout(t *testing.T) {
+	blocking := CheckerFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	h := &Handler{Ready: []Checker{blocking}, Timeout: 1}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+}
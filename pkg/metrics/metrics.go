@@ -0,0 +1,166 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/enerBit/redsumer/v3/pkg/consumer"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// metrics_LAG_TIMEOUT bounds the XINFO GROUPS calls made to collect the group lag on a scrape.
+const metrics_LAG_TIMEOUT = 5 * time.Second
+
+// Collector records the events of consumers and producers as Prometheus metrics.
+// It implements consumer.Metrics, producer.Metrics and prometheus.Collector: set it as the Metrics
+// of the consumers and producers, and register it once with a prometheus.Registerer.
+//
+//	collector := metrics.NewCollector("orders")
+//	prometheus.MustRegister(collector)
+//	c := &consumer.Consumer{ /* ... */ Metrics: collector}
+//	collector.WatchLag(c)
+type Collector struct {
+	produced        *prometheus.CounterVec
+	produceFailures *prometheus.CounterVec
+	consumed        *prometheus.CounterVec
+	acked           *prometheus.CounterVec
+	ackFailures     *prometheus.CounterVec
+	handlerDuration *prometheus.HistogramVec
+	groupRecoveries *prometheus.CounterVec
+	lag             *prometheus.Desc
+
+	consumersMu sync.Mutex
+	consumers   []*consumer.Consumer
+}
+
+// NewCollector creates a Collector whose metrics are named redsumer_*, prefixed by namespace when not empty.
+func NewCollector(namespace string) *Collector {
+	name := func(name string) string {
+		return prometheus.BuildFQName(namespace, "redsumer", name)
+	}
+
+	return &Collector{
+		produced: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: name("messages_produced_total"),
+			Help: "Messages added to a stream.",
+		}, []string{"stream"}),
+		produceFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: name("produce_failures_total"),
+			Help: "Messages that could not be added to a stream.",
+		}, []string{"stream"}),
+		consumed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: name("messages_consumed_total"),
+			Help: "Messages returned by Consume, by phase: new, pending or autoclaim.",
+		}, []string{"stream", "group", "phase"}),
+		acked: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: name("messages_acked_total"),
+			Help: "Messages acknowledged in a stream.",
+		}, []string{"stream", "group"}),
+		ackFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: name("ack_failures_total"),
+			Help: "Acknowledgements that failed, including the messages no longer pending in the group.",
+		}, []string{"stream", "group"}),
+		handlerDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    name("handler_duration_seconds"),
+			Help:    "Duration of the handler calls, by result: success or error.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"stream", "group", "result"}),
+		groupRecoveries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: name("group_recoveries_total"),
+			Help: "Groups created again after Valkey answered NOGROUP.",
+		}, []string{"group"}),
+		lag: prometheus.NewDesc(name("group_lag"),
+			"Entries of a stream not yet delivered to the group, collected with XINFO GROUPS.",
+			[]string{"stream", "group"}, nil),
+	}
+}
+
+// WatchLag makes the Collector report the lag of the group of a consumer on every scrape.
+// The consumer must be initialized before the next scrape.
+func (m *Collector) WatchLag(c *consumer.Consumer) {
+	m.consumersMu.Lock()
+	defer m.consumersMu.Unlock()
+
+	m.consumers = append(m.consumers, c)
+}
+
+// Produced counts a message sent to a stream.
+func (m *Collector) Produced(stream string, err error) {
+	if err != nil {
+		m.produceFailures.WithLabelValues(stream).Inc()
+		return
+	}
+	m.produced.WithLabelValues(stream).Inc()
+}
+
+// Consumed counts the messages returned by a phase of Consume.
+func (m *Collector) Consumed(stream string, group string, phase string, count int) {
+	m.consumed.WithLabelValues(stream, group, phase).Add(float64(count))
+}
+
+// Handled observes the duration of a handler call.
+func (m *Collector) Handled(stream string, group string, duration time.Duration, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	m.handlerDuration.WithLabelValues(stream, group, result).Observe(duration.Seconds())
+}
+
+// Acked counts an acknowledgement, or its failure.
+func (m *Collector) Acked(stream string, group string, err error) {
+	if err != nil {
+		m.ackFailures.WithLabelValues(stream, group).Inc()
+		return
+	}
+	m.acked.WithLabelValues(stream, group).Inc()
+}
+
+// GroupRecovered counts a group created again after a NOGROUP error.
+func (m *Collector) GroupRecovered(group string) {
+	m.groupRecoveries.WithLabelValues(group).Inc()
+}
+
+// Describe sends the descriptors of the metrics of the Collector.
+func (m *Collector) Describe(ch chan<- *prometheus.Desc) {
+	m.vectors(func(c prometheus.Collector) { c.Describe(ch) })
+	ch <- m.lag
+}
+
+// Collect sends the metrics of the Collector, querying the lag of the watched consumers.
+// The lag of a consumer is left out of the scrape when it cannot be queried, and the lag of a group
+// is queried through the first consumer that answers when several consumers of the group are watched.
+func (m *Collector) Collect(ch chan<- prometheus.Metric) {
+	m.vectors(func(c prometheus.Collector) { c.Collect(ch) })
+
+	m.consumersMu.Lock()
+	consumers := append([]*consumer.Consumer(nil), m.consumers...)
+	m.consumersMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), metrics_LAG_TIMEOUT)
+	defer cancel()
+	// Consumers of the same group share its lag, which must be reported once per stream
+	collected := make(map[[2]string]struct{})
+	for _, c := range consumers {
+		lag, err := c.Lag(ctx)
+		if err != nil {
+			continue
+		}
+		for stream, n := range lag {
+			series := [2]string{stream, c.GroupName}
+			if _, ok := collected[series]; ok {
+				continue
+			}
+			collected[series] = struct{}{}
+			ch <- prometheus.MustNewConstMetric(m.lag, prometheus.GaugeValue, float64(n), stream, c.GroupName)
+		}
+	}
+}
+
+// vectors calls f with each metric vector of the Collector.
+func (m *Collector) vectors(f func(prometheus.Collector)) {
+	for _, c := range []prometheus.Collector{m.produced, m.produceFailures, m.consumed, m.acked, m.ackFailures, m.handlerDuration, m.groupRecoveries} {
+		f(c)
+	}
+}
@@ -0,0 +1,178 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/enerBit/redsumer/v3/pkg/client"
+	"github.com/enerBit/redsumer/v3/pkg/consumer"
+	errors_custom "github.com/enerBit/redsumer/v3/pkg/errors"
+	"github.com/enerBit/redsumer/v3/pkg/producer"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/valkey-io/valkey-go"
+	"github.com/valkey-io/valkey-go/mock"
+	"go.uber.org/mock/gomock"
+)
+
+const (
+	streamName   string = "stream-test"
+	groupName    string = "group-test"
+	consumerName string = "consumer-test"
+)
+
+func TestCollectorConsumer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	db := mock.NewClient(ctrl)
+
+	messageId := "1676389477-0"
+	entry := mock.ValkeyArray(mock.ValkeyString(messageId), mock.ValkeyArray(mock.ValkeyString("key"), mock.ValkeyString("value")))
+
+	gomock.InOrder(
+		db.EXPECT().Do(gomock.Any(), mock.Match("XREADGROUP", "GROUP", groupName, consumerName, "COUNT", "1", "STREAMS", streamName, ">")).Return(mock.Result(mock.ValkeyMap(map[string]valkey.ValkeyMessage{streamName: mock.ValkeyArray(entry)}))),
+		db.EXPECT().Do(gomock.Any(), mock.Match("XPENDING", streamName, groupName, "IDLE", "0", messageId, messageId, "1", consumerName)).Return(mock.Result(mock.ValkeyArray(valkey.ValkeyMessage{}))),
+		db.EXPECT().Do(gomock.Any(), mock.Match("XACK", streamName, groupName, messageId)).DoAndReturn(func(ctx context.Context, cmd valkey.Completed) valkey.ValkeyResult {
+			cancel()
+			return mock.Result(mock.ValkeyInt64(1))
+		}),
+	)
+	db.EXPECT().Do(gomock.Any(), mock.Match("XREADGROUP", "GROUP", groupName, consumerName, "COUNT", "1", "STREAMS", streamName, ">")).Return(mock.Result(mock.ValkeyNil())).AnyTimes()
+	db.EXPECT().Do(gomock.Any(), mock.Match("XINFO", "GROUPS", streamName)).Return(mock.Result(mock.ValkeyArray(mock.ValkeyMap(map[string]valkey.ValkeyMessage{
+		"name": mock.ValkeyString(groupName),
+		"lag":  mock.ValkeyInt64(42),
+	}))))
+
+	collector := NewCollector("")
+	c := &consumer.Consumer{
+		Client:              &client.ClientArgs{Instance: db},
+		StreamName:          streamName,
+		GroupName:           groupName,
+		ConsumerName:        consumerName,
+		BatchSizeNewMessage: 1,
+		Metrics:             collector,
+	}
+	collector.WatchLag(c)
+
+	err := c.Run(ctx, func(ctx context.Context, message valkey.XRangeEntry) error {
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	consumed := testutil.ToFloat64(collector.consumed.WithLabelValues(streamName, groupName, consumer.PhaseNew))
+	if consumed != 1 {
+		t.Fatalf("expected 1 message consumed, got %v", consumed)
+	}
+	acked := testutil.ToFloat64(collector.acked.WithLabelValues(streamName, groupName))
+	if acked != 1 {
+		t.Fatalf("expected 1 message acked, got %v", acked)
+	}
+
+	expected := `
+# HELP redsumer_group_lag Entries of a stream not yet delivered to the group, collected with XINFO GROUPS.
+# TYPE redsumer_group_lag gauge
+redsumer_group_lag{group="group-test",stream="stream-test"} 42
+`
+	err = testutil.CollectAndCompare(collector, strings.NewReader(expected), "redsumer_group_lag")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if n := testutil.CollectAndCount(collector.handlerDuration); n != 1 {
+		t.Fatalf("expected 1 handler histogram, got %d", n)
+	}
+}
+
+func TestCollectorEvents(t *testing.T) {
+	collector := NewCollector("app")
+
+	collector.Produced(streamName, nil)
+	collector.Produced(streamName, errors_custom.ErrStreamNotFound)
+	collector.Acked(streamName, groupName, errors_custom.ErrNoAckedMessage)
+	collector.Handled(streamName, groupName, time.Second, errors.New("failed"))
+	collector.GroupRecovered(groupName)
+
+	for metric, value := range map[string]float64{
+		"produced":        testutil.ToFloat64(collector.produced.WithLabelValues(streamName)),
+		"produceFailures": testutil.ToFloat64(collector.produceFailures.WithLabelValues(streamName)),
+		"ackFailures":     testutil.ToFloat64(collector.ackFailures.WithLabelValues(streamName, groupName)),
+		"groupRecoveries": testutil.ToFloat64(collector.groupRecoveries.WithLabelValues(groupName)),
+	} {
+		if value != 1 {
+			t.Errorf("expected %s to be 1, got %v", metric, value)
+		}
+	}
+
+	err := testutil.CollectAndCompare(collector, strings.NewReader(`
+# HELP app_redsumer_group_recoveries_total Groups created again after Valkey answered NOGROUP.
+# TYPE app_redsumer_group_recoveries_total counter
+app_redsumer_group_recoveries_total{group="group-test"} 1
+`), "app_redsumer_group_recoveries_total")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+}
+
+func TestCollectorProducer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	db := mock.NewClient(ctrl)
+
+	db.EXPECT().DoMulti(ctx, gomock.Any(), gomock.Any()).Return([]valkey.ValkeyResult{
+		mock.Result(mock.ValkeyString("1676389477000-0")),
+		mock.Result(mock.ValkeyNil()),
+	})
+
+	collector := NewCollector("")
+	p := producer.Producer{
+		Client:  &client.ClientArgs{Instance: db},
+		Metrics: collector,
+	}
+
+	p.ProduceBatch(ctx, streamName, []map[string]string{{"key": "first"}, {"key": "second"}})
+
+	if produced := testutil.ToFloat64(collector.produced.WithLabelValues(streamName)); produced != 1 {
+		t.Fatalf("expected 1 message produced, got %v", produced)
+	}
+	if failures := testutil.ToFloat64(collector.produceFailures.WithLabelValues(streamName)); failures != 1 {
+		t.Fatalf("expected 1 produce failure, got %v", failures)
+	}
+}
+
+func TestCollectorLagSharedGroup(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mock.NewClient(ctrl)
+	db.EXPECT().Do(gomock.Any(), mock.Match("XINFO", "GROUPS", streamName)).Return(mock.Result(mock.ValkeyArray(mock.ValkeyMap(map[string]valkey.ValkeyMessage{
+		"name": mock.ValkeyString(groupName),
+		"lag":  mock.ValkeyInt64(7),
+	})))).AnyTimes()
+
+	collector := NewCollector("")
+	for _, name := range []string{"consumer-a", "consumer-b"} {
+		collector.WatchLag(&consumer.Consumer{
+			Client:       &client.ClientArgs{Instance: db},
+			StreamName:   streamName,
+			GroupName:    groupName,
+			ConsumerName: name,
+		})
+	}
+
+	err := testutil.CollectAndCompare(collector, strings.NewReader(`
+# HELP redsumer_group_lag Entries of a stream not yet delivered to the group, collected with XINFO GROUPS.
+# TYPE redsumer_group_lag gauge
+redsumer_group_lag{group="group-test",stream="stream-test"} 7
+`), "redsumer_group_lag")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+}
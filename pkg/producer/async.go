@@ -0,0 +1,259 @@
+package producer
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	errors_custom "github.com/enerBit/redsumer/v3/pkg/errors"
+	"github.com/enerBit/redsumer/v3/pkg/tracing"
+	"github.com/valkey-io/valkey-go"
+)
+
+const (
+	producer_DEFAULT_BATCH_SIZE     = 100
+	producer_DEFAULT_FLUSH_INTERVAL = 100 * time.Millisecond
+)
+
+// Report is the delivery report of a message sent by an AsyncProducer.
+type Report struct {
+	Result
+
+	Stream  string
+	Message map[string]string
+
+	// fields are the fields written: Message with the trace context of Send.
+	fields map[string]string
+}
+
+// AsyncProducer sends messages in the background: Send buffers them in memory, and they are
+// flushed to Valkey in a single pipelined round trip when BatchSize messages are buffered or
+// every FlushInterval, with the Trim and NoMkStream settings of the producer.
+// Call InitAsyncProducer before sending messages, and Close to flush the buffer and stop.
+type AsyncProducer struct {
+	Producer *Producer
+
+	// BatchSize is the number of buffered messages that triggers a flush.
+	// Values lower than 1 are treated as 100.
+	BatchSize int
+	// FlushInterval is the maximum time a message stays buffered. Zero means 100 milliseconds.
+	FlushInterval time.Duration
+	// QueueSize is the number of messages Send can hand over to the background flusher
+	// before it blocks. Values lower than 1 are treated as BatchSize.
+	QueueSize int
+	// DeliveryReports sends a Report for every message to Reports, once it is flushed.
+	// Reports must then be drained, since flushing waits for the reports to be received.
+	DeliveryReports bool
+
+	queue   chan Report
+	flushes chan chan struct{}
+	reports chan Report
+	closing chan struct{}
+	done    chan struct{}
+
+	// flushCtx is the context of the flushes, cancelled when Close gives up waiting.
+	flushCtx    context.Context
+	cancelFlush context.CancelFunc
+
+	mu     sync.RWMutex
+	closed bool
+	// senders are the Send calls in progress, waited for by the flusher before its last flush.
+	senders sync.WaitGroup
+}
+
+// InitAsyncProducer starts the background flusher.
+func (a *AsyncProducer) InitAsyncProducer() {
+	batchSize := a.batchSize()
+	queueSize := a.QueueSize
+	if queueSize < 1 {
+		queueSize = batchSize
+	}
+
+	a.queue = make(chan Report, queueSize)
+	a.flushes = make(chan chan struct{})
+	a.reports = make(chan Report, batchSize)
+	a.closing = make(chan struct{})
+	a.done = make(chan struct{})
+	a.flushCtx, a.cancelFlush = context.WithCancel(context.Background())
+
+	go a.loop()
+}
+
+func (a *AsyncProducer) batchSize() int {
+	if a.BatchSize < 1 {
+		return producer_DEFAULT_BATCH_SIZE
+	}
+	return a.BatchSize
+}
+
+// Reports returns the channel of delivery reports, closed once the producer is closed.
+// It only receives reports when DeliveryReports is set.
+func (a *AsyncProducer) Reports() <-chan Report {
+	return a.reports
+}
+
+// Send buffers a message for the specified stream, along with the trace context of ctx.
+// It blocks while the queue is full, until the context is done or Close is called.
+// It returns ErrProducerClosed once Close has been called.
+func (a *AsyncProducer) Send(ctx context.Context, message map[string]string, streamName string) error {
+	a.mu.RLock()
+	if a.closed {
+		a.mu.RUnlock()
+		return errors_custom.ErrProducerClosed
+	}
+	a.senders.Add(1)
+	a.mu.RUnlock()
+	defer a.senders.Done()
+
+	select {
+	case a.queue <- Report{Stream: streamName, Message: message, fields: tracing.Inject(ctx, a.Producer.Propagator, message)}:
+		return nil
+	case <-a.closing:
+		return errors_custom.ErrProducerClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Flush sends the buffered messages and waits until they are written, or the context is done.
+func (a *AsyncProducer) Flush(ctx context.Context) error {
+	a.mu.RLock()
+	closed := a.closed
+	a.mu.RUnlock()
+	if closed {
+		return errors_custom.ErrProducerClosed
+	}
+
+	flushed := make(chan struct{})
+	select {
+	case a.flushes <- flushed:
+	case <-a.done:
+		return errors_custom.ErrProducerClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting messages, flushes the buffered ones and waits for them to be written.
+// If the context is done first, the pending flush is cancelled and its messages are reported
+// with the context error. The Producer and its client are left open.
+func (a *AsyncProducer) Close(ctx context.Context) error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return errors_custom.ErrProducerClosed
+	}
+	a.closed = true
+	a.mu.Unlock()
+	close(a.closing)
+
+	select {
+	case <-a.done:
+		a.cancelFlush()
+		return nil
+	case <-ctx.Done():
+		a.cancelFlush()
+		return ctx.Err()
+	}
+}
+
+// loop buffers the queued messages and flushes them until the producer is closed.
+func (a *AsyncProducer) loop() {
+	defer close(a.done)
+	defer close(a.reports)
+
+	interval := a.FlushInterval
+	if interval <= 0 {
+		interval = producer_DEFAULT_FLUSH_INTERVAL
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	batchSize := a.batchSize()
+	buffer := make([]Report, 0, batchSize)
+	for {
+		select {
+		case message := <-a.queue:
+			buffer = append(buffer, message)
+			if len(buffer) >= batchSize {
+				buffer = a.flush(buffer)
+			}
+		case <-ticker.C:
+			buffer = a.flush(buffer)
+		case flushed := <-a.flushes:
+			buffer = a.drain(buffer)
+			buffer = a.flush(buffer)
+			close(flushed)
+		case <-a.closing:
+			// Every Send in progress returns promptly once closing is closed
+			a.senders.Wait()
+			buffer = a.drain(buffer)
+			a.flush(buffer)
+			return
+		}
+	}
+}
+
+// drain moves every queued message to the buffer, flushing it whenever it is full.
+func (a *AsyncProducer) drain(buffer []Report) []Report {
+	batchSize := a.batchSize()
+	for {
+		select {
+		case message := <-a.queue:
+			buffer = append(buffer, message)
+			if len(buffer) >= batchSize {
+				buffer = a.flush(buffer)
+			}
+		default:
+			return buffer
+		}
+	}
+}
+
+// flush writes the buffered messages in a single pipelined round trip, reports their delivery,
+// and returns the emptied buffer.
+func (a *AsyncProducer) flush(buffer []Report) []Report {
+	if len(buffer) == 0 {
+		return buffer
+	}
+
+	cmds := make([]valkey.Completed, 0, len(buffer))
+	sent := make([]int, 0, len(buffer))
+	for i := range buffer {
+		cmd, err := a.Producer.xadd(buffer[i].fields, buffer[i].Stream, ProduceOptions{})
+		if err != nil {
+			buffer[i].Err = err
+			continue
+		}
+		cmds = append(cmds, cmd)
+		sent = append(sent, i)
+	}
+
+	if len(cmds) != 0 {
+		ctx, span := a.Producer.startSpan(a.flushCtx, "", len(cmds))
+		for i, reply := range a.Producer.Client.Instance.DoMulti(ctx, cmds...) {
+			buffer[sent[i]].Result = result(reply.ToString())
+		}
+		span.End()
+	}
+
+	for _, report := range buffer {
+		a.Producer.produced(report.Stream, report.Err)
+	}
+
+	if a.DeliveryReports {
+		for _, report := range buffer {
+			a.reports <- report
+		}
+	}
+
+	clear(buffer)
+	return buffer[:0]
+}
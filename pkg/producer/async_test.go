@@ -0,0 +1,219 @@
+package producer
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/enerBit/redsumer/v3/pkg/client"
+	errors_custom "github.com/enerBit/redsumer/v3/pkg/errors"
+	"github.com/valkey-io/valkey-go"
+	"github.com/valkey-io/valkey-go/mock"
+	"go.uber.org/mock/gomock"
+)
+
+// xaddReplies answers every XADD of a pipeline with a sequential ID, and records the batch sizes.
+type xaddReplies struct {
+	mu      sync.Mutex
+	next    int
+	batches []int
+}
+
+func (x *xaddReplies) reply(ctx context.Context, cmds ...valkey.Completed) []valkey.ValkeyResult {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.batches = append(x.batches, len(cmds))
+	results := make([]valkey.ValkeyResult, len(cmds))
+	for i := range cmds {
+		results[i] = mock.Result(mock.ValkeyString("1676389477000-" + strconv.Itoa(x.next)))
+		x.next++
+	}
+	return results
+}
+
+func TestAsyncProducerBatchSize(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mock.NewClient(ctrl)
+	replies := &xaddReplies{}
+	db.EXPECT().DoMulti(gomock.Any(), gomock.Any()).DoAndReturn(replies.reply).AnyTimes()
+	db.EXPECT().DoMulti(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(replies.reply).AnyTimes()
+
+	a := &AsyncProducer{
+		Producer:        &Producer{Client: &client.ClientArgs{Instance: db}},
+		BatchSize:       2,
+		FlushInterval:   time.Hour,
+		DeliveryReports: true,
+	}
+	a.InitAsyncProducer()
+
+	var reports []Report
+	received := make(chan struct{})
+	go func() {
+		defer close(received)
+		for report := range a.Reports() {
+			reports = append(reports, report)
+		}
+	}()
+
+	ctx := context.Background()
+	for i := range 3 {
+		err := a.Send(ctx, map[string]string{"key": strconv.Itoa(i)}, streamName)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+	}
+
+	err := a.Close(ctx)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	<-received
+
+	if len(replies.batches) != 2 || replies.batches[0] != 2 || replies.batches[1] != 1 {
+		t.Fatalf("expected batches of 2 and 1 messages, got %v", replies.batches)
+	}
+	if len(reports) != 3 {
+		t.Fatalf("expected 3 reports, got %d", len(reports))
+	}
+	for i, report := range reports {
+		if report.Err != nil || report.ID != "1676389477000-"+strconv.Itoa(i) || report.Stream != streamName || report.Message["key"] != strconv.Itoa(i) {
+			t.Fatalf("unexpected report %+v", report)
+		}
+	}
+
+	err = a.Send(ctx, map[string]string{"key": "late"}, streamName)
+	if !errors.Is(err, errors_custom.ErrProducerClosed) {
+		t.Fatalf("expected %v, got %v", errors_custom.ErrProducerClosed, err)
+	}
+}
+
+func TestAsyncProducerFlushInterval(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mock.NewClient(ctrl)
+	replies := &xaddReplies{}
+	db.EXPECT().DoMulti(gomock.Any(), gomock.Any()).DoAndReturn(replies.reply).AnyTimes()
+
+	a := &AsyncProducer{
+		Producer:        &Producer{Client: &client.ClientArgs{Instance: db}},
+		FlushInterval:   10 * time.Millisecond,
+		DeliveryReports: true,
+	}
+	a.InitAsyncProducer()
+	defer a.Close(context.Background())
+
+	err := a.Send(context.Background(), map[string]string{"key": "value"}, streamName)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	select {
+	case report := <-a.Reports():
+		if report.Err != nil || report.ID != "1676389477000-0" {
+			t.Fatalf("unexpected report %+v", report)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("message not flushed")
+	}
+}
+
+func TestAsyncProducerFlush(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mock.NewClient(ctrl)
+	db.EXPECT().DoMulti(gomock.Any(), gomock.Any()).Return([]valkey.ValkeyResult{
+		mock.Result(mock.ValkeyError("ERR error")),
+	})
+
+	a := &AsyncProducer{
+		Producer:      &Producer{Client: &client.ClientArgs{Instance: db}},
+		FlushInterval: time.Hour,
+	}
+	a.InitAsyncProducer()
+
+	ctx := context.Background()
+	err := a.Send(ctx, map[string]string{"key": "value"}, streamName)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	err = a.Flush(ctx)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	err = a.Close(ctx)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	err = a.Flush(ctx)
+	if !errors.Is(err, errors_custom.ErrProducerClosed) {
+		t.Fatalf("expected %v, got %v", errors_custom.ErrProducerClosed, err)
+	}
+}
+
+func TestAsyncProducerCloseStalled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mock.NewClient(ctrl)
+	replies := &xaddReplies{}
+	db.EXPECT().DoMulti(gomock.Any(), gomock.Any()).DoAndReturn(replies.reply).AnyTimes()
+
+	// Reports are not drained, so the flusher stalls and Send blocks on the full queue
+	a := &AsyncProducer{
+		Producer:        &Producer{Client: &client.ClientArgs{Instance: db}},
+		BatchSize:       1,
+		QueueSize:       1,
+		FlushInterval:   time.Hour,
+		DeliveryReports: true,
+	}
+	a.InitAsyncProducer()
+
+	blocked := make(chan error)
+	go func() {
+		for i := range 4 {
+			err := a.Send(context.Background(), map[string]string{"key": strconv.Itoa(i)}, streamName)
+			if err != nil {
+				blocked <- err
+				return
+			}
+		}
+		blocked <- nil
+	}()
+
+	select {
+	case err := <-blocked:
+		t.Fatalf("expected Send to block, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	closed := make(chan error)
+	go func() { closed <- a.Close(ctx) }()
+
+	select {
+	case err := <-closed:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Close ignored its deadline")
+	}
+	err := <-blocked
+	if !errors.Is(err, errors_custom.ErrProducerClosed) {
+		t.Fatalf("expected %v, got %v", errors_custom.ErrProducerClosed, err)
+	}
+
+	for range a.Reports() {
+	}
+}
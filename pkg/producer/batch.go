@@ -0,0 +1,117 @@
+package producer
+
+import (
+	"context"
+
+	errors_custom "github.com/enerBit/redsumer/v3/pkg/errors"
+	"github.com/enerBit/redsumer/v3/pkg/tracing"
+	"github.com/valkey-io/valkey-go"
+)
+
+// Result is the outcome of a message produced in a batch.
+type Result struct {
+	// ID is the ID of the new entry, empty when Err is set.
+	ID string
+	// Err is the error that prevented the message from being added.
+	Err error
+}
+
+// ProduceBatch sends messages to the specified stream in a single pipelined round trip,
+// with the Trim and NoMkStream settings of the producer.
+// It returns one Result per message, in the order of the messages. Each message succeeds or fails
+// on its own; use ProduceBatchAtomic when the batch must be written as a whole.
+func (p *Producer) ProduceBatch(ctx context.Context, streamName string, messages []map[string]string) []Result {
+	ctx, span := p.startSpan(ctx, streamName, len(messages))
+
+	cmds, err := p.batch(ctx, streamName, messages)
+	if err != nil {
+		results := failed(len(messages), err)
+		p.producedResults(streamName, results)
+		tracing.End(span, err)
+		return results
+	}
+
+	results := make([]Result, len(messages))
+	for i, reply := range p.Client.Instance.DoMulti(ctx, cmds...) {
+		results[i] = result(reply.ToString())
+	}
+	p.producedResults(streamName, results)
+	span.End()
+	return results
+}
+
+// ProduceBatchAtomic sends messages to the specified stream in a MULTI/EXEC transaction,
+// in a single pipelined round trip, with the Trim and NoMkStream settings of the producer.
+// The returned error is set when the transaction was not executed, in which case no message was added.
+// Otherwise it returns one Result per message, in the order of the messages. Valkey does not roll back
+// a transaction, so a message failing when the transaction executes, such as with ErrStreamNotFound,
+// does not prevent the others from being added.
+func (p *Producer) ProduceBatchAtomic(ctx context.Context, streamName string, messages []map[string]string) (results []Result, err error) {
+	ctx, span := p.startSpan(ctx, streamName, len(messages))
+	defer func() {
+		if err != nil {
+			p.producedResults(streamName, failed(len(messages), err))
+		} else {
+			p.producedResults(streamName, results)
+		}
+		tracing.End(span, err)
+	}()
+
+	cmds, err := p.batch(ctx, streamName, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	cmds = append(append([]valkey.Completed{p.Client.Instance.B().Multi().Build()}, cmds...), p.Client.Instance.B().Exec().Build())
+	replies := p.Client.Instance.DoMulti(ctx, cmds...)
+	for _, reply := range replies {
+		err = reply.Error()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	executed, err := replies[len(replies)-1].ToArray()
+	if err != nil {
+		return nil, err
+	}
+
+	results = make([]Result, len(messages))
+	for i, reply := range executed {
+		results[i] = result(reply.ToString())
+	}
+	return results, nil
+}
+
+// batch builds the XADD commands of messages, injecting the trace context of ctx.
+func (p *Producer) batch(ctx context.Context, streamName string, messages []map[string]string) ([]valkey.Completed, error) {
+	cmds := make([]valkey.Completed, len(messages))
+	for i, message := range messages {
+		cmd, err := p.xadd(tracing.Inject(ctx, p.Propagator, message), streamName, ProduceOptions{})
+		if err != nil {
+			return nil, err
+		}
+		cmds[i] = cmd
+	}
+	return cmds, nil
+}
+
+// result builds the Result of an XADD reply.
+func result(id string, err error) Result {
+	if valkey.IsValkeyNil(err) {
+		err = errors_custom.ErrStreamNotFound
+	}
+	if err != nil {
+		return Result{Err: err}
+	}
+	return Result{ID: id}
+}
+
+// failed returns n results failed with the same error.
+func failed(n int, err error) []Result {
+	results := make([]Result, n)
+	for i := range results {
+		results[i].Err = err
+	}
+	return results
+}
@@ -0,0 +1,122 @@
+package producer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/enerBit/redsumer/v3/pkg/client"
+	errors_custom "github.com/enerBit/redsumer/v3/pkg/errors"
+	"github.com/valkey-io/valkey-go"
+	"github.com/valkey-io/valkey-go/mock"
+	"go.uber.org/mock/gomock"
+)
+
+func TestProduceBatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	db := mock.NewClient(ctrl)
+
+	db.EXPECT().DoMulti(ctx,
+		mock.Match("XADD", streamName, "MAXLEN", "10", "*", "key", "first"),
+		mock.Match("XADD", streamName, "MAXLEN", "10", "*", "key", "second"),
+	).Return([]valkey.ValkeyResult{
+		mock.Result(mock.ValkeyString("1676389477000-0")),
+		mock.Result(mock.ValkeyError("ERR error")),
+	})
+
+	p := Producer{
+		Client: &client.ClientArgs{Instance: db},
+		Trim:   &Trim{MaxLen: 10},
+	}
+	results := p.ProduceBatch(ctx, streamName, []map[string]string{{"key": "first"}, {"key": "second"}})
+
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	if results[0].ID != "1676389477000-0" || results[0].Err != nil {
+		t.Fatalf("unexpected first result %+v", results[0])
+	}
+	if results[1].ID != "" || results[1].Err == nil {
+		t.Fatalf("unexpected second result %+v", results[1])
+	}
+}
+
+func TestProduceBatchInvalidTrim(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	p := Producer{
+		Client: &client.ClientArgs{Instance: mock.NewClient(ctrl)},
+		Trim:   &Trim{},
+	}
+	results := p.ProduceBatch(context.Background(), streamName, []map[string]string{{"key": "first"}, {"key": "second"}})
+
+	for _, result := range results {
+		if !errors.Is(result.Err, errors_custom.ErrInvalidConfig) {
+			t.Fatalf("expected %v, got %v", errors_custom.ErrInvalidConfig, result.Err)
+		}
+	}
+}
+
+func TestProduceBatchAtomic(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	db := mock.NewClient(ctrl)
+
+	db.EXPECT().DoMulti(ctx,
+		mock.Match("MULTI"),
+		mock.Match("XADD", streamName, "NOMKSTREAM", "*", "key", "first"),
+		mock.Match("XADD", streamName, "NOMKSTREAM", "*", "key", "second"),
+		mock.Match("EXEC"),
+	).Return([]valkey.ValkeyResult{
+		mock.Result(mock.ValkeyString("OK")),
+		mock.Result(mock.ValkeyString("QUEUED")),
+		mock.Result(mock.ValkeyString("QUEUED")),
+		mock.Result(mock.ValkeyArray(mock.ValkeyString("1676389477000-0"), mock.ValkeyNil())),
+	})
+
+	p := Producer{
+		Client:     &client.ClientArgs{Instance: db},
+		NoMkStream: true,
+	}
+	results, err := p.ProduceBatchAtomic(ctx, streamName, []map[string]string{{"key": "first"}, {"key": "second"}})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if results[0].ID != "1676389477000-0" || results[0].Err != nil {
+		t.Fatalf("unexpected first result %+v", results[0])
+	}
+	if !errors.Is(results[1].Err, errors_custom.ErrStreamNotFound) {
+		t.Fatalf("expected %v, got %v", errors_custom.ErrStreamNotFound, results[1].Err)
+	}
+}
+
+func TestProduceBatchAtomicAborted(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	db := mock.NewClient(ctrl)
+
+	db.EXPECT().DoMulti(ctx, gomock.Any(), gomock.Any(), gomock.Any()).Return([]valkey.ValkeyResult{
+		mock.Result(mock.ValkeyString("OK")),
+		mock.Result(mock.ValkeyError("ERR wrong number of arguments for 'xadd' command")),
+		mock.Result(mock.ValkeyError("EXECABORT Transaction discarded because of previous errors.")),
+	})
+
+	p := Producer{
+		Client: &client.ClientArgs{Instance: db},
+	}
+	results, err := p.ProduceBatchAtomic(ctx, streamName, []map[string]string{{"key": "first"}})
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+	if results != nil {
+		t.Fatalf("expected nil results, got %+v", results)
+	}
+}
@@ -0,0 +1,29 @@
+package producer
+
+import (
+	"context"
+	"fmt"
+
+	errors_custom "github.com/enerBit/redsumer/v3/pkg/errors"
+)
+
+// Health checks that the producer can work: Valkey answers PING and every stream in Streams exists.
+// It returns the first failed check, wrapping ErrStreamNotFound for a missing stream.
+func (p *Producer) Health(ctx context.Context) error {
+	err := p.Client.Instance.Do(ctx, p.Client.Instance.B().Ping().Build()).Error()
+	if err != nil {
+		return err
+	}
+
+	for _, stream := range p.Streams {
+		exists, err := p.Client.Instance.Do(ctx, p.Client.Instance.B().Exists().Key(stream).Build()).ToInt64()
+		if err != nil {
+			return err
+		}
+		if exists != 1 {
+			return fmt.Errorf("%w: %s", errors_custom.ErrStreamNotFound, stream)
+		}
+	}
+
+	return nil
+}
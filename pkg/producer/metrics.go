@@ -0,0 +1,23 @@
+package producer
+
+// Metrics receives the outcome of the messages sent by a producer, to record them as metrics.
+// Its methods may be called from several goroutines and must be safe for concurrent use.
+// metrics.Collector implements it for Prometheus.
+type Metrics interface {
+	// Produced is called once per message sent to a stream, with a nil error when it was added.
+	Produced(stream string, err error)
+}
+
+// produced reports the outcome of a message to Metrics.
+func (p *Producer) produced(streamName string, err error) {
+	if p.Metrics != nil {
+		p.Metrics.Produced(streamName, err)
+	}
+}
+
+// producedResults reports the outcome of a batch of messages to Metrics.
+func (p *Producer) producedResults(streamName string, results []Result) {
+	for _, result := range results {
+		p.produced(streamName, result.Err)
+	}
+}
@@ -2,27 +2,130 @@ package producer
 
 import (
 	"context"
+	"time"
 
 	"github.com/enerBit/redsumer/v3/pkg/client"
+	errors_custom "github.com/enerBit/redsumer/v3/pkg/errors"
+	"github.com/enerBit/redsumer/v3/pkg/tracing"
+	"github.com/valkey-io/valkey-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// producer_AUTO_ID makes Valkey generate the ID of a new entry.
+const producer_AUTO_ID = "*"
+
 type Producer struct {
 	Client *client.ClientArgs
+
+	// Streams are the streams the producer writes to, checked for existence by Health.
+	Streams []string
+
+	// Trim bounds the size of the streams on every message produced. When nil, streams are never trimmed.
+	Trim *Trim
+	// NoMkStream fails with ErrStreamNotFound instead of creating a missing stream (XADD NOMKSTREAM).
+	NoMkStream bool
+
+	// TracerProvider creates the spans around XADD calls. When nil, the global provider is used.
+	TracerProvider trace.TracerProvider
+	// Propagator injects the trace context of the producing span into the messages, in fields
+	// prefixed by tracing.FieldPrefix. When nil, the global propagator is used.
+	Propagator propagation.TextMapPropagator
+	// Metrics receives the outcome of every message produced. Metrics are disabled when nil.
+	Metrics Metrics
+}
+
+// ProduceOptions are the per-message settings of ProduceWithOptions.
+type ProduceOptions struct {
+	// ID is the ID of the new entry, such as "1676389477000-0" or "1676389477000-*".
+	// It must be greater than the last ID of the stream. When empty, Valkey generates it.
+	ID string
+	// Trim bounds the size of the stream, instead of the Trim of the producer.
+	Trim *Trim
+	// NoMkStream fails with ErrStreamNotFound instead of creating a missing stream.
+	// It applies when either the producer or the options set it.
+	NoMkStream bool
 }
 
 // Produce sends a message to the specified stream.
 // It takes a context and a message map as input.
 // Returns an error if there was a problem sending the message.
 func (p *Producer) Produce(ctx context.Context, message map[string]string, streamName string) error {
-	cmd := p.Client.Instance.B().Xadd().Key(streamName).Id("*").FieldValue()
-	for k, v := range message {
-		cmd.FieldValue(k, v)
-	}
-
-	err := p.Client.Instance.Do(ctx, cmd.Build()).Error()
+	_, err := p.ProduceWithOptions(ctx, message, streamName, ProduceOptions{})
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// ProduceWithOptions sends a message to the specified stream with per-message settings.
+// It returns the ID of the new entry, as assigned by Valkey, or an error if there was a problem sending the message.
+func (p *Producer) ProduceWithOptions(ctx context.Context, message map[string]string, streamName string, opts ProduceOptions) (string, error) {
+	ctx, span := p.startSpan(ctx, streamName, 1)
+
+	cmd, err := p.xadd(tracing.Inject(ctx, p.Propagator, message), streamName, opts)
+	if err != nil {
+		p.produced(streamName, err)
+		tracing.End(span, err)
+		return "", err
+	}
+
+	id, err := p.Client.Instance.Do(ctx, cmd).ToString()
+	if valkey.IsValkeyNil(err) {
+		err = errors_custom.ErrStreamNotFound
+	}
+	p.produced(streamName, err)
+	span.SetAttributes(tracing.AttributeMessageID.String(id))
+	tracing.End(span, err)
+	return id, err
+}
+
+// startSpan starts the span of an XADD call, or of a pipeline of count XADD calls, to a stream.
+// The stream is left out of the span when empty, for pipelines to several streams.
+func (p *Producer) startSpan(ctx context.Context, streamName string, count int) (context.Context, trace.Span) {
+	attributes := []attribute.KeyValue{
+		tracing.System,
+		tracing.AttributeOperation.String("send"),
+	}
+	if streamName != "" {
+		attributes = append(attributes, tracing.AttributeDestination.String(streamName))
+	}
+	if count > 1 {
+		attributes = append(attributes, tracing.AttributeMessageCount.Int(count))
+	}
+	return tracing.Start(ctx, p.TracerProvider, "XADD", trace.WithSpanKind(trace.SpanKindProducer), trace.WithAttributes(attributes...))
+}
+
+// xadd builds the XADD command of a message, with the trimming and stream creation settings
+// of the options, or of the producer when the options do not set them.
+func (p *Producer) xadd(message map[string]string, streamName string, opts ProduceOptions) (valkey.Completed, error) {
+	var args []string
+	if p.NoMkStream || opts.NoMkStream {
+		args = append(args, "NOMKSTREAM")
+	}
+
+	trim := p.Trim
+	if opts.Trim != nil {
+		trim = opts.Trim
+	}
+	if trim != nil {
+		trimArgs, err := trim.args(time.Now())
+		if err != nil {
+			return valkey.Completed{}, err
+		}
+		args = append(args, trimArgs...)
+	}
+
+	id := opts.ID
+	if id == "" {
+		id = producer_AUTO_ID
+	}
+	args = append(args, id)
+	for k, v := range message {
+		args = append(args, k, v)
+	}
+
+	return p.Client.Instance.B().Arbitrary("XADD").Keys(streamName).Args(args...).Build(), nil
+}
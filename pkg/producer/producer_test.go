@@ -2,10 +2,17 @@ package producer
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/enerBit/redsumer/v3/pkg/client"
+	errors_custom "github.com/enerBit/redsumer/v3/pkg/errors"
+	"github.com/valkey-io/valkey-go"
 	"github.com/valkey-io/valkey-go/mock"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/mock/gomock"
 )
 
@@ -60,4 +67,138 @@ func TestProduceError(t *testing.T) {
 	if err == nil {
 		t.Fatalf("expected error, got nil")
 	}
-}
\ No newline at end of file
+}
+
+func TestHealth(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	db := mock.NewClient(ctrl)
+
+	db.EXPECT().Do(ctx, mock.Match("PING")).Return(mock.Result(mock.ValkeyString("PONG"))).Times(2)
+	db.EXPECT().Do(ctx, mock.Match("EXISTS", streamName)).Return(mock.Result(mock.ValkeyInt64(1)))
+	db.EXPECT().Do(ctx, mock.Match("EXISTS", streamName)).Return(mock.Result(mock.ValkeyInt64(0)))
+	p := Producer{
+		Client:  &client.ClientArgs{Instance: db},
+		Streams: []string{streamName},
+	}
+
+	err := p.Health(ctx)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	err = p.Health(ctx)
+	if !errors.Is(err, errors_custom.ErrStreamNotFound) {
+		t.Fatalf("expected %v, got %v", errors_custom.ErrStreamNotFound, err)
+	}
+}
+
+func TestProduceWithOptions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	db := mock.NewClient(ctrl)
+
+	message := map[string]string{
+		"key": "value",
+	}
+
+	db.EXPECT().Do(ctx, mock.Match("XADD", streamName, "*", "key", "value")).Return(mock.Result(mock.ValkeyString("1676389477000-0")))
+	db.EXPECT().Do(ctx, mock.Match("XADD", streamName, "1676389477000-1", "key", "value")).Return(mock.Result(mock.ValkeyString("1676389477000-1")))
+	p := Producer{
+		Client: &client.ClientArgs{Instance: db},
+	}
+
+	id, err := p.ProduceWithOptions(ctx, message, streamName, ProduceOptions{})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if id != "1676389477000-0" {
+		t.Fatalf("expected 1676389477000-0, got %s", id)
+	}
+
+	id, err = p.ProduceWithOptions(ctx, message, streamName, ProduceOptions{ID: "1676389477000-1"})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if id != "1676389477000-1" {
+		t.Fatalf("expected 1676389477000-1, got %s", id)
+	}
+}
+
+func TestProduceTrim(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	db := mock.NewClient(ctrl)
+
+	message := map[string]string{
+		"key": "value",
+	}
+
+	db.EXPECT().Do(ctx, mock.Match("XADD", streamName, "NOMKSTREAM", "MAXLEN", "~", "1000", "*", "key", "value")).Return(mock.Result(mock.ValkeyString("1676389477000-0")))
+	db.EXPECT().Do(ctx, mock.Match("XADD", streamName, "NOMKSTREAM", "MINID", "1676389477000-0", "*", "key", "value")).Return(mock.Result(mock.ValkeyNil()))
+	p := Producer{
+		Client:     &client.ClientArgs{Instance: db},
+		Trim:       &Trim{MaxLen: 1000, Approximate: true},
+		NoMkStream: true,
+	}
+
+	err := p.Produce(ctx, message, streamName)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	_, err = p.ProduceWithOptions(ctx, message, streamName, ProduceOptions{Trim: &Trim{MinID: "1676389477000-0"}})
+	if !errors.Is(err, errors_custom.ErrStreamNotFound) {
+		t.Fatalf("expected %v, got %v", errors_custom.ErrStreamNotFound, err)
+	}
+}
+
+func TestProduceTracing(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	db := mock.NewClient(ctrl)
+
+	var traceparent string
+	db.EXPECT().Do(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, cmd valkey.Completed) valkey.ValkeyResult {
+		args := cmd.Commands()
+		for i := 3; i+1 < len(args); i += 2 {
+			if args[i] == "redsumer-traceparent" {
+				traceparent = args[i+1]
+			}
+		}
+		return mock.Result(mock.ValkeyString("1676389477000-0"))
+	})
+	p := Producer{
+		Client:         &client.ClientArgs{Instance: db},
+		TracerProvider: provider,
+		Propagator:     propagation.TraceContext{},
+	}
+
+	message := map[string]string{
+		"key": "value",
+	}
+	err := p.Produce(context.Background(), message, streamName)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if len(message) != 1 {
+		t.Fatalf("expected message unchanged, got %v", message)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 || spans[0].Name() != "XADD" || spans[0].SpanKind() != trace.SpanKindProducer {
+		t.Fatalf("expected a producer XADD span, got %v", spans)
+	}
+	if traceparent != "00-"+spans[0].SpanContext().TraceID().String()+"-"+spans[0].SpanContext().SpanID().String()+"-01" {
+		t.Fatalf("expected the traceparent of the XADD span, got %q", traceparent)
+	}
+}
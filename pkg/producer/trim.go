@@ -0,0 +1,61 @@
+package producer
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	errors_custom "github.com/enerBit/redsumer/v3/pkg/errors"
+)
+
+// Trim bounds the size of a stream, evicting its oldest entries as new ones are added by XADD.
+// Exactly one of MaxLen, MinID and Retention must be set.
+type Trim struct {
+	// MaxLen keeps at most MaxLen entries in the stream (XADD MAXLEN).
+	MaxLen int64
+	// MinID evicts the entries with IDs lower than MinID (XADD MINID).
+	MinID string
+	// Retention evicts the entries older than Retention, using as MINID the time of every XADD minus Retention.
+	Retention time.Duration
+
+	// Approximate lets Valkey trim lazily, only whole macro nodes, which is much cheaper (~).
+	// The stream may then hold a few more entries than requested.
+	Approximate bool
+	// Limit caps the number of entries evicted by a single XADD. It requires Approximate.
+	// Zero uses the Valkey default.
+	Limit int64
+}
+
+// args returns the XADD trimming arguments, computing a time-based MINID from now.
+func (t *Trim) args(now time.Time) ([]string, error) {
+	var strategy, threshold string
+	set := 0
+	if t.MaxLen > 0 {
+		strategy, threshold = "MAXLEN", strconv.FormatInt(t.MaxLen, 10)
+		set++
+	}
+	if t.MinID != "" {
+		strategy, threshold = "MINID", t.MinID
+		set++
+	}
+	if t.Retention > 0 {
+		strategy, threshold = "MINID", strconv.FormatInt(now.Add(-t.Retention).UnixMilli(), 10)+"-0"
+		set++
+	}
+	if set != 1 {
+		return nil, fmt.Errorf("%w: exactly one of MaxLen, MinID and Retention must be set", errors_custom.ErrInvalidConfig)
+	}
+	if t.Limit > 0 && !t.Approximate {
+		return nil, fmt.Errorf("%w: trim Limit requires Approximate", errors_custom.ErrInvalidConfig)
+	}
+
+	args := []string{strategy}
+	if t.Approximate {
+		args = append(args, "~")
+	}
+	args = append(args, threshold)
+	if t.Limit > 0 {
+		args = append(args, "LIMIT", strconv.FormatInt(t.Limit, 10))
+	}
+	return args, nil
+}
@@ -0,0 +1,76 @@
+package producer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/enerBit/redsumer/v3/pkg/client"
+	errors_custom "github.com/enerBit/redsumer/v3/pkg/errors"
+	"github.com/valkey-io/valkey-go/mock"
+	"go.uber.org/mock/gomock"
+)
+
+func TestTrimArgs(t *testing.T) {
+	now := time.UnixMilli(1676389477000)
+	tests := []struct {
+		trim Trim
+		args string
+	}{
+		{Trim{MaxLen: 1000}, "MAXLEN 1000"},
+		{Trim{MaxLen: 1000, Approximate: true, Limit: 100}, "MAXLEN ~ 1000 LIMIT 100"},
+		{Trim{MinID: "1676389476000-0", Approximate: true}, "MINID ~ 1676389476000-0"},
+		{Trim{Retention: time.Hour}, "MINID 1676385877000-0"},
+	}
+	for _, test := range tests {
+		args, err := test.trim.args(now)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if strings.Join(args, " ") != test.args {
+			t.Fatalf("expected %s, got %s", test.args, strings.Join(args, " "))
+		}
+	}
+
+	for _, trim := range []Trim{{}, {MaxLen: 1, MinID: "0-1"}, {MaxLen: 1, Limit: 10}} {
+		_, err := trim.args(now)
+		if !errors.Is(err, errors_custom.ErrInvalidConfig) {
+			t.Fatalf("expected %v for %+v, got %v", errors_custom.ErrInvalidConfig, trim, err)
+		}
+	}
+}
+
+func TestXaddCommand(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	p := Producer{
+		Client: &client.ClientArgs{Instance: mock.NewClient(ctrl)},
+	}
+	tests := []struct {
+		opts ProduceOptions
+		cmd  string
+	}{
+		{ProduceOptions{}, "XADD stream-test * key value"},
+		{ProduceOptions{ID: "1-1", NoMkStream: true}, "XADD stream-test NOMKSTREAM 1-1 key value"},
+		{ProduceOptions{Trim: &Trim{MaxLen: 1000}}, "XADD stream-test MAXLEN 1000 * key value"},
+		{ProduceOptions{Trim: &Trim{MaxLen: 1000, Approximate: true, Limit: 100}}, "XADD stream-test MAXLEN ~ 1000 LIMIT 100 * key value"},
+		{ProduceOptions{Trim: &Trim{MinID: "1676389476000-0", Approximate: true}, NoMkStream: true}, "XADD stream-test NOMKSTREAM MINID ~ 1676389476000-0 * key value"},
+		{ProduceOptions{Trim: &Trim{MinID: "1676389476000-0"}}, "XADD stream-test MINID 1676389476000-0 * key value"},
+	}
+	for _, test := range tests {
+		cmd, err := p.xadd(map[string]string{"key": "value"}, streamName, test.opts)
+		if err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+		if strings.Join(cmd.Commands(), " ") != test.cmd {
+			t.Fatalf("expected %s, got %s", test.cmd, strings.Join(cmd.Commands(), " "))
+		}
+	}
+
+	_, err := p.xadd(map[string]string{"key": "value"}, streamName, ProduceOptions{Trim: &Trim{}})
+	if !errors.Is(err, errors_custom.ErrInvalidConfig) {
+		t.Fatalf("expected %v, got %v", errors_custom.ErrInvalidConfig, err)
+	}
+}
@@ -0,0 +1,115 @@
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope of the spans created by redsumer.
+const ScopeName = "github.com/enerBit/redsumer/v3"
+
+// FieldPrefix is prepended to the propagation keys, such as traceparent and tracestate,
+// to name the entry fields holding the trace context, so they never clash with message fields.
+const FieldPrefix = "redsumer-"
+
+// Messaging attributes set on the spans.
+const (
+	AttributeSystem       = attribute.Key("messaging.system")
+	AttributeOperation    = attribute.Key("messaging.operation.name")
+	AttributeDestination  = attribute.Key("messaging.destination.name")
+	AttributeGroup        = attribute.Key("messaging.consumer.group.name")
+	AttributeMessageID    = attribute.Key("messaging.message.id")
+	AttributeMessageCount = attribute.Key("messaging.batch.message_count")
+)
+
+// System is the messaging.system attribute of the spans.
+var System = AttributeSystem.String("valkey")
+
+// Carrier adapts the fields of an entry to propagation.TextMapCarrier, adding FieldPrefix to the keys.
+type Carrier map[string]string
+
+// Get returns the value of a propagation key.
+func (c Carrier) Get(key string) string {
+	return c[FieldPrefix+key]
+}
+
+// Set stores the value of a propagation key.
+func (c Carrier) Set(key string, value string) {
+	c[FieldPrefix+key] = value
+}
+
+// Keys returns the propagation keys stored in the fields.
+func (c Carrier) Keys() []string {
+	keys := make([]string, 0, 2)
+	for field := range c {
+		if key, ok := strings.CutPrefix(field, FieldPrefix); ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Tracer returns the redsumer tracer of a provider, or of the global provider when nil.
+func Tracer(provider trace.TracerProvider) trace.Tracer {
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	return provider.Tracer(ScopeName)
+}
+
+// Start starts a span with the redsumer tracer of a provider. The returned context carries the span
+// only when it is recording, so ctx is passed on unchanged when tracing is disabled.
+func Start(ctx context.Context, provider trace.TracerProvider, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	spanCtx, span := Tracer(provider).Start(ctx, name, opts...)
+	if !span.IsRecording() {
+		return ctx, span
+	}
+	return spanCtx, span
+}
+
+// Propagator returns a propagator, or the global propagator when nil.
+func Propagator(propagator propagation.TextMapPropagator) propagation.TextMapPropagator {
+	if propagator == nil {
+		return otel.GetTextMapPropagator()
+	}
+	return propagator
+}
+
+// Inject returns a copy of the fields of a message with the trace context of ctx added.
+// The fields are returned unchanged when ctx has no trace context to propagate.
+func Inject(ctx context.Context, propagator propagation.TextMapPropagator, fields map[string]string) map[string]string {
+	carrier := Carrier{}
+	Propagator(propagator).Inject(ctx, carrier)
+	if len(carrier) == 0 {
+		return fields
+	}
+
+	injected := make(map[string]string, len(fields)+len(carrier))
+	for k, v := range fields {
+		injected[k] = v
+	}
+	for k, v := range carrier {
+		injected[k] = v
+	}
+	return injected
+}
+
+// Extract returns ctx with the trace context propagated in the fields of an entry.
+func Extract(ctx context.Context, propagator propagation.TextMapPropagator, fields map[string]string) context.Context {
+	return Propagator(propagator).Extract(ctx, Carrier(fields))
+}
+
+// End records err on a span, when not nil, and ends the span.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,72 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestInjectExtract(t *testing.T) {
+	provider := sdktrace.NewTracerProvider()
+	propagator := propagation.TraceContext{}
+
+	ctx, span := Tracer(provider).Start(context.Background(), "produce")
+	defer span.End()
+
+	message := map[string]string{"key": "value"}
+	fields := Inject(ctx, propagator, message)
+	if len(message) != 1 {
+		t.Fatalf("expected message unchanged, got %v", message)
+	}
+	if fields["key"] != "value" || fields[FieldPrefix+"traceparent"] == "" {
+		t.Fatalf("expected message fields and traceparent, got %v", fields)
+	}
+
+	keys := Carrier(fields).Keys()
+	if len(keys) != 1 || keys[0] != "traceparent" {
+		t.Fatalf("expected [traceparent], got %v", keys)
+	}
+
+	remote := trace.SpanContextFromContext(Extract(context.Background(), propagator, fields))
+	if !remote.IsRemote() || remote.TraceID() != span.SpanContext().TraceID() || remote.SpanID() != span.SpanContext().SpanID() {
+		t.Fatalf("expected %v, got %v", span.SpanContext(), remote)
+	}
+}
+
+func TestInjectWithoutSpan(t *testing.T) {
+	message := map[string]string{"key": "value"}
+	fields := Inject(context.Background(), propagation.TraceContext{}, message)
+	if len(fields) != 1 || fields["key"] != "value" {
+		t.Fatalf("expected message unchanged, got %v", fields)
+	}
+}
+
+func TestStart(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	ctx := context.Background()
+	spanCtx, span := Start(ctx, provider, "XADD")
+	if trace.SpanFromContext(spanCtx) != span {
+		t.Fatalf("expected the context to carry the span")
+	}
+	End(span, errors.New("failed"))
+
+	ended := recorder.Ended()
+	if len(ended) != 1 || ended[0].Name() != "XADD" || ended[0].Status().Code != codes.Error {
+		t.Fatalf("expected an XADD span with an error status, got %v", ended)
+	}
+
+	disabled := sdktrace.NewTracerProvider(sdktrace.WithSampler(sdktrace.NeverSample()))
+	spanCtx, span = Start(ctx, disabled, "XADD")
+	span.End()
+	if spanCtx != ctx {
+		t.Fatalf("expected the context unchanged for a span not recording")
+	}
+}
@@ -0,0 +1,96 @@
+package typed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/enerBit/redsumer/v3/pkg/codec"
+	"github.com/enerBit/redsumer/v3/pkg/consumer"
+	errors_custom "github.com/enerBit/redsumer/v3/pkg/errors"
+)
+
+// Message is a consumed message with its value decoded.
+type Message[T any] struct {
+	consumer.Message
+	Value T
+}
+
+// Handler processes a decoded message. It has the semantics of consumer.StreamHandler.
+type Handler[T any] func(ctx context.Context, message Message[T]) error
+
+// DecodeErrorHandler handles a message that could not be decoded, with the decoding error.
+// When it returns nil the message is acknowledged and dropped; otherwise it is left pending.
+type DecodeErrorHandler func(ctx context.Context, message consumer.Message, err error) error
+
+// Consumer reads values of type T, decoded by Codec, through a consumer.Consumer.
+type Consumer[T any] struct {
+	Consumer *consumer.Consumer
+	Codec    codec.Codec
+
+	// OnDecodeError handles the messages that cannot be decoded, for example by logging them or
+	// producing them to a quarantine stream. When nil, they are left pending, so they are delivered
+	// again and reach the dead-letter stream of the consumer, if any, after MaxDeliveries.
+	OnDecodeError DecodeErrorHandler
+}
+
+// decode decodes the value of a message. Errors wrap ErrDecode.
+func (c *Consumer[T]) decode(message consumer.Message) (Message[T], error) {
+	decoded := Message[T]{Message: message}
+	err := c.Codec.Decode(message.FieldValues, &decoded.Value)
+	if err != nil {
+		if !errors.Is(err, errors_custom.ErrDecode) {
+			err = fmt.Errorf("%w: %w", errors_custom.ErrDecode, err)
+		}
+		return decoded, err
+	}
+	return decoded, nil
+}
+
+// decodeError hands an undecodable message to OnDecodeError. It returns nil when the message
+// must be acknowledged, and an error when it must be left pending.
+func (c *Consumer[T]) decodeError(ctx context.Context, message consumer.Message, err error) error {
+	if c.OnDecodeError == nil {
+		return err
+	}
+	return c.OnDecodeError(ctx, message, err)
+}
+
+// Consume reads messages like consumer.Consumer.ConsumeStreams and decodes them.
+// Messages that cannot be decoded are not returned: they are handed to OnDecodeError, and
+// acknowledged when it returns nil.
+func (c *Consumer[T]) Consume(ctx context.Context) ([]Message[T], error) {
+	messages, err := c.Consumer.ConsumeStreams(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	decoded := make([]Message[T], 0, len(messages))
+	for _, message := range messages {
+		m, err := c.decode(message)
+		if err == nil {
+			decoded = append(decoded, m)
+			continue
+		}
+
+		if c.decodeError(ctx, message, err) == nil {
+			err = c.Consumer.AcknowledgeMessageInStream(ctx, message.Stream, message.ID)
+			if err != nil && err != errors_custom.ErrNoAckedMessage {
+				return nil, err
+			}
+		}
+	}
+	return decoded, nil
+}
+
+// Run processes messages like consumer.Consumer.RunStreams, calling the handler with decoded messages.
+// Messages that cannot be decoded are handed to OnDecodeError instead, and acknowledged when it returns nil.
+func (c *Consumer[T]) Run(ctx context.Context, handler Handler[T]) error {
+	return c.Consumer.RunStreams(ctx, func(ctx context.Context, message consumer.Message) error {
+		m, err := c.decode(message)
+		if err != nil {
+			return c.decodeError(ctx, message, err)
+		}
+		return handler(ctx, m)
+	})
+}
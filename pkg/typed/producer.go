@@ -0,0 +1,30 @@
+package typed
+
+import (
+	"context"
+
+	"github.com/enerBit/redsumer/v3/pkg/codec"
+	"github.com/enerBit/redsumer/v3/pkg/producer"
+)
+
+// Producer sends values of type T, encoded by Codec, through a producer.Producer.
+type Producer[T any] struct {
+	Producer *producer.Producer
+	Codec    codec.Codec
+}
+
+// Produce encodes a value and sends it to the specified stream.
+func (p *Producer[T]) Produce(ctx context.Context, value T, streamName string) error {
+	_, err := p.ProduceWithOptions(ctx, value, streamName, producer.ProduceOptions{})
+	return err
+}
+
+// ProduceWithOptions encodes a value and sends it to the specified stream with per-message settings.
+// It returns the ID of the new entry.
+func (p *Producer[T]) ProduceWithOptions(ctx context.Context, value T, streamName string, opts producer.ProduceOptions) (string, error) {
+	message, err := p.Codec.Encode(value)
+	if err != nil {
+		return "", err
+	}
+	return p.Producer.ProduceWithOptions(ctx, message, streamName, opts)
+}
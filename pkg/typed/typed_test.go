@@ -0,0 +1,157 @@
+package typed
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/enerBit/redsumer/v3/pkg/client"
+	"github.com/enerBit/redsumer/v3/pkg/codec"
+	"github.com/enerBit/redsumer/v3/pkg/consumer"
+	errors_custom "github.com/enerBit/redsumer/v3/pkg/errors"
+	"github.com/enerBit/redsumer/v3/pkg/producer"
+	"github.com/valkey-io/valkey-go"
+	"github.com/valkey-io/valkey-go/mock"
+	"go.uber.org/mock/gomock"
+)
+
+const (
+	streamName   string = "stream-test"
+	groupName    string = "group-test"
+	consumerName string = "consumer-test"
+)
+
+type order struct {
+	ID string `redsumer:"id"`
+}
+
+// entry builds a stream entry with a single field.
+func entry(id string, field string, value string) valkey.ValkeyMessage {
+	return mock.ValkeyArray(mock.ValkeyString(id), mock.ValkeyArray(mock.ValkeyString(field), mock.ValkeyString(value)))
+}
+
+func TestProducerProduce(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	db := mock.NewClient(ctrl)
+
+	db.EXPECT().Do(ctx, mock.Match("XADD", streamName, "*", "id", "1")).Return(mock.Result(mock.ValkeyString("1676389477000-0")))
+
+	p := Producer[order]{
+		Producer: &producer.Producer{Client: &client.ClientArgs{Instance: db}},
+		Codec:    codec.Fields{},
+	}
+	id, err := p.ProduceWithOptions(ctx, order{ID: "1"}, streamName, producer.ProduceOptions{})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if id != "1676389477000-0" {
+		t.Fatalf("expected 1676389477000-0, got %s", id)
+	}
+}
+
+func TestConsumerConsume(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	db := mock.NewClient(ctrl)
+
+	db.EXPECT().Do(ctx, mock.Match("XREADGROUP", "GROUP", groupName, consumerName, "COUNT", "2", "STREAMS", streamName, ">")).Return(mock.Result(mock.ValkeyMap(map[string]valkey.ValkeyMessage{
+		streamName: mock.ValkeyArray(
+			entry("1676389477000-0", codec.FieldPayload, `{"ID":"1"}`),
+			entry("1676389477000-1", codec.FieldPayload, `{`),
+		),
+	})))
+	db.EXPECT().Do(ctx, mock.Match("XACK", streamName, groupName, "1676389477000-1")).Return(mock.Result(mock.ValkeyInt64(1)))
+
+	var undecodable []string
+	c := Consumer[order]{
+		Consumer: &consumer.Consumer{
+			Client:              &client.ClientArgs{Instance: db},
+			StreamName:          streamName,
+			GroupName:           groupName,
+			ConsumerName:        consumerName,
+			BatchSizeNewMessage: 2,
+		},
+		Codec: codec.JSON{},
+		OnDecodeError: func(ctx context.Context, message consumer.Message, err error) error {
+			if !errors.Is(err, errors_custom.ErrDecode) {
+				t.Errorf("expected %v, got %v", errors_custom.ErrDecode, err)
+			}
+			undecodable = append(undecodable, message.ID)
+			return nil
+		},
+	}
+
+	messages, err := c.Consume(ctx)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if len(messages) != 1 || messages[0].ID != "1676389477000-0" || messages[0].Value.ID != "1" {
+		t.Fatalf("unexpected messages %+v", messages)
+	}
+	if len(undecodable) != 1 || undecodable[0] != "1676389477000-1" {
+		t.Fatalf("unexpected undecodable messages %v", undecodable)
+	}
+}
+
+func TestConsumerRun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	db := mock.NewClient(ctrl)
+
+	db.EXPECT().Do(gomock.Any(), mock.Match("XREADGROUP", "GROUP", groupName, consumerName, "COUNT", "2", "STREAMS", streamName, ">")).Return(mock.Result(mock.ValkeyMap(map[string]valkey.ValkeyMessage{
+		streamName: mock.ValkeyArray(
+			entry("1676389477000-0", "other", "2"),
+			entry("1676389477000-1", "id", "1"),
+		),
+	})))
+	db.EXPECT().Do(gomock.Any(), mock.Match("XPENDING", streamName, groupName, "IDLE", "0", "1676389477000-1", "1676389477000-1", "1", consumerName)).Return(mock.Result(mock.ValkeyArray(valkey.ValkeyMessage{})))
+	db.EXPECT().Do(gomock.Any(), mock.Match("XPENDING", streamName, groupName, "IDLE", "0", "1676389477000-0", "1676389477000-0", "1", consumerName)).Return(mock.Result(mock.ValkeyArray(valkey.ValkeyMessage{})))
+	db.EXPECT().Do(gomock.Any(), mock.Match("XACK", streamName, groupName, "1676389477000-1")).DoAndReturn(func(ctx context.Context, cmd valkey.Completed) valkey.ValkeyResult {
+		cancel()
+		return mock.Result(mock.ValkeyInt64(1))
+	})
+	db.EXPECT().Do(gomock.Any(), mock.Match("XREADGROUP", "GROUP", groupName, consumerName, "COUNT", "2", "STREAMS", streamName, ">")).Return(mock.Result(mock.ValkeyNil())).AnyTimes()
+
+	c := Consumer[order]{
+		Consumer: &consumer.Consumer{
+			Client:              &client.ClientArgs{Instance: db},
+			StreamName:          streamName,
+			GroupName:           groupName,
+			ConsumerName:        consumerName,
+			BatchSizeNewMessage: 2,
+		},
+		Codec: strictFields{},
+	}
+
+	var handled []string
+	err := c.Run(ctx, func(ctx context.Context, message Message[order]) error {
+		handled = append(handled, message.Value.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if len(handled) != 1 || handled[0] != "1" {
+		t.Fatalf("unexpected handled messages %v", handled)
+	}
+}
+
+// strictFields is codec.Fields failing on entries without an id field.
+type strictFields struct {
+	codec.Fields
+}
+
+func (s strictFields) Decode(fields map[string]string, v any) error {
+	if _, ok := fields["id"]; !ok {
+		return errors.New("no id field")
+	}
+	return s.Fields.Decode(fields, v)
+}